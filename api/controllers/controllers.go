@@ -6,5 +6,6 @@ import "go.uber.org/fx"
 var Module = fx.Options(
 	fx.Provide(NewUserController),
 	fx.Provide(NewJWTAuthController),
+	fx.Provide(NewOIDCAuthController),
 	fx.Provide(NewEchoController),
 )
@@ -20,9 +20,9 @@ func (s *EchoController) Echo(c *gin.Context) {
 	req := struct {
 		Input string `form:"input" binding:"required"`
 	}{}
-	err := c.ShouldBindQuery(&req)
-	if err != nil {
+	if err := BindQuery(c, &req); err != nil {
 		OnError(c, err)
+		return
 	}
 	OnSuccess(c, req.Input)
 }
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/constants"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+)
+
+// ProblemContentType content type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// defaultStatusCodes http status of error codes, overridden by errors.status_codes of config
+var defaultStatusCodes = map[int]int{
+	errors.InputError.Code():           http.StatusBadRequest,
+	errors.AuthError.Code():            http.StatusUnauthorized,
+	errors.DBError.Code():              http.StatusInternalServerError,
+	errors.NotFound.Code():             http.StatusNotFound,
+	errors.ConfigError.Code():          http.StatusInternalServerError,
+	errors.Conflict.Code():             http.StatusConflict,
+	errors.Forbidden.Code():            http.StatusForbidden,
+	errors.MethodNotAllowed.Code():     http.StatusMethodNotAllowed,
+	errors.TooManyRequests.Code():      http.StatusTooManyRequests,
+	errors.RequestTooLarge.Code():      http.StatusRequestEntityTooLarge,
+	errors.UnsupportedMediaType.Code(): http.StatusUnsupportedMediaType,
+	errors.UpstreamError.Code():        http.StatusBadGateway,
+	errors.UnknownError.Code():         http.StatusInternalServerError,
+}
+
+// errorRendering how errors are rendered, replaced on config reload
+type errorRendering struct {
+	statusCodes map[int]int
+	problemJSON bool
+}
+
+var rendering atomic.Value
+
+func init() {
+	rendering.Store(&errorRendering{statusCodes: defaultStatusCodes})
+}
+
+// ConfigureErrors sets status codes and format of error responses,
+// codes of cfg.StatusCodes are validated by config
+func ConfigureErrors(cfg config.ErrorsConfig) {
+	r := &errorRendering{
+		statusCodes: make(map[int]int, len(defaultStatusCodes)+len(cfg.StatusCodes)),
+		problemJSON: cfg.ProblemJSON,
+	}
+	for code, status := range defaultStatusCodes {
+		r.statusCodes[code] = status
+	}
+	for code, status := range cfg.StatusCodes {
+		if n, err := strconv.Atoi(code); err == nil {
+			r.statusCodes[n] = status
+		}
+	}
+	rendering.Store(r)
+}
+
+// StatusOf returns http status of error code, 500 for codes not in the table
+func StatusOf(codeErr *errors.CodeError) int {
+	if status, ok := rendering.Load().(*errorRendering).statusCodes[codeErr.Code()]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Problem problem details of RFC 7807, code is the extension member of error code
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance,omitempty"`
+	Code     int    `json:"code"`
+}
+
+// wantsProblem returns whether error is rendered as problem details,
+// it's enabled by errors.problem_json or requested by Accept header
+func wantsProblem(c *gin.Context) bool {
+	return rendering.Load().(*errorRendering).problemJSON ||
+		strings.Contains(c.GetHeader("Accept"), ProblemContentType)
+}
+
+// OnError makes error response with status of its code and aborts pending handlers,
+// it's the only way errors are rendered, middlewares use it as well
+func OnError(c *gin.Context, err error) {
+	codeErr := errors.Err2Code(err)
+	c.Set(constants.ErrorCodeGinContextKey, codeErr)
+	c.Abort()
+	if c.Writer.Written() {
+		logging.CtxLogger(c).Sugar().Warnf("get error but already has response body: %s", err)
+		return
+	}
+	status := StatusOf(codeErr)
+	if wantsProblem(c) {
+		c.Render(status, problemRender{Problem{
+			Type:     "about:blank",
+			Title:    codeErr.Desc(),
+			Status:   status,
+			Detail:   err.Error(),
+			Instance: c.Request.URL.Path,
+			Code:     codeErr.Code(),
+		}})
+		return
+	}
+	c.JSON(status, &Response{
+		Code:    codeErr.Code(),
+		Message: err.Error(),
+	})
+}
+
+// problemRender renders problem as json with ProblemContentType
+type problemRender struct {
+	problem Problem
+}
+
+// Render ...
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return render.JSON{Data: r.problem}.Render(w)
+}
+
+// WriteContentType ...
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header()["Content-Type"] = []string{ProblemContentType}
+}
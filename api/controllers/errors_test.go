@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/gin-gonic/gin"
+)
+
+func renderError(err error, accept string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/user/1", func(c *gin.Context) {
+		OnError(c, err)
+		OnSuccess(c, "ignored after error")
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/user/1", nil)
+	req.Header.Set("Accept", accept)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestOnError(t *testing.T) {
+	defer ConfigureErrors(config.ErrorsConfig{})
+	cases := map[error]int{
+		errors.CodeErrorf(errors.InputError, "bad"):        http.StatusBadRequest,
+		errors.CodeErrorf(errors.AuthError, "expired"):     http.StatusUnauthorized,
+		errors.CodeErrorf(errors.Forbidden, "denied"):      http.StatusForbidden,
+		errors.CodeErrorf(errors.NotFound, "no user"):      http.StatusNotFound,
+		errors.CodeErrorf(errors.Conflict, "version"):      http.StatusConflict,
+		errors.CodeErrorf(errors.DBError, "db"):            http.StatusInternalServerError,
+		errors.Errorf("plain error"):                       http.StatusInternalServerError,
+		errors.CodeErrorf(errors.NewCodeError(99, ""), ""): http.StatusInternalServerError,
+	}
+	for err, status := range cases {
+		w := renderError(err, "")
+		var resp Response
+		if w.Code != status || json.Unmarshal(w.Body.Bytes(), &resp) != nil ||
+			resp.Code != errors.Err2Code(err).Code() || resp.Message != err.Error() {
+			t.Error(err, w.Code, w.Body.String())
+		}
+	}
+
+	ConfigureErrors(config.ErrorsConfig{StatusCodes: map[string]int{"6": 422}})
+	if w := renderError(errors.CodeErrorf(errors.Conflict, "version"), ""); w.Code != http.StatusUnprocessableEntity {
+		t.Error("status should be overridden by config", w.Code)
+	}
+	if w := renderError(errors.CodeErrorf(errors.NotFound, ""), ""); w.Code != http.StatusNotFound {
+		t.Error("default status should be kept", w.Code)
+	}
+}
+
+func TestOnErrorProblem(t *testing.T) {
+	defer ConfigureErrors(config.ErrorsConfig{})
+	check := func(w *httptest.ResponseRecorder) {
+		var p Problem
+		if w.Header().Get("Content-Type") != ProblemContentType || json.Unmarshal(w.Body.Bytes(), &p) != nil {
+			t.Fatal(w.Header(), w.Body.String())
+		}
+		if p.Status != http.StatusNotFound || p.Code != errors.NotFound.Code() || p.Title != "Not found" ||
+			p.Detail == "" || p.Instance != "/user/1" || p.Type != "about:blank" {
+			t.Errorf("unexpected problem %+v", p)
+		}
+	}
+	check(renderError(errors.CodeErrorf(errors.NotFound, "no user"), "application/problem+json, application/json"))
+	ConfigureErrors(config.ErrorsConfig{ProblemJSON: true})
+	check(renderError(errors.CodeErrorf(errors.NotFound, "no user"), ""))
+}
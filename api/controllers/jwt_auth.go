@@ -1,9 +1,14 @@
 package controllers
 
 import (
+	"net/http"
+
+	"github.com/dean2032/go-project-layout/constants"
 	"github.com/dean2032/go-project-layout/services"
 	"github.com/dean2032/go-project-layout/utils/logging"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // JWTAuthController struct
@@ -23,18 +28,94 @@ func NewJWTAuthController(
 	}
 }
 
-// SignIn signs in user
+// SignIn signs in user with email and password
 func (jwt *JWTAuthController) SignIn(c *gin.Context) {
-	logging.Info("SignIn route called")
-	// Currently not checking for username and password
-	// Can add the logic later if necessary.
-	user, _ := jwt.userService.GetOneUser(uint(1))
-	token := jwt.service.CreateToken(user)
-	OnSuccess(c, token)
+	var req struct {
+		Email    string `json:"email" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := BindJSON(c, &req); err != nil {
+		OnError(c, err)
+		return
+	}
+	user, err := jwt.userService.Authenticate(req.Email, req.Password)
+	if err != nil {
+		logging.CtxLogger(c).Info("sign in fail", zap.String("email", req.Email), zap.Error(err))
+		OnError(c, err)
+		return
+	}
+	tokens, err := jwt.service.IssueTokens(user)
+	if err != nil {
+		logging.CtxLogger(c).Error("issue tokens fail", zap.Uint("user_id", user.ID), zap.Error(err))
+		OnError(c, err)
+		return
+	}
+	OnSuccess(c, tokens)
+}
+
+// Refresh exchanges refresh token for new access token and refresh token
+func (jwt *JWTAuthController) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := BindJSON(c, &req); err != nil {
+		OnError(c, err)
+		return
+	}
+	tokens, err := jwt.service.Refresh(req.RefreshToken)
+	if err != nil {
+		logging.CtxLogger(c).Info("refresh token fail", zap.Error(err))
+		OnError(c, err)
+		return
+	}
+	OnSuccess(c, tokens)
 }
 
-// Register registers user
+// Register registers user with email and password, the created user is responded
 func (jwt *JWTAuthController) Register(c *gin.Context) {
-	logging.Info("Register route called")
-	OnSuccess(c, "register route")
+	var req struct {
+		Email string `json:"email" binding:"required,email,max=100"`
+		Name  string `json:"name" binding:"max=20"`
+		// bcrypt uses at most 72 bytes of password
+		Password string `json:"password" binding:"required,min=8,max=72"`
+	}
+	if err := BindJSON(c, &req); err != nil {
+		OnError(c, err)
+		return
+	}
+	txHandle := c.MustGet(constants.DBTransaction).(*gorm.DB)
+	user, err := jwt.userService.WithTx(txHandle).Register(req.Email, req.Name, req.Password)
+	if err != nil {
+		logging.CtxLogger(c).Info("register fail", zap.String("email", req.Email), zap.Error(err))
+		OnError(c, err)
+		return
+	}
+	OnSuccess(c, user)
+}
+
+// Logout revokes the access token of request, and refresh tokens of the sign in if refresh_token is given
+func (jwt *JWTAuthController) Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	// body is optional
+	if c.Request.ContentLength != 0 {
+		if err := BindJSON(c, &req); err != nil {
+			OnError(c, err)
+			return
+		}
+	}
+	principal, _ := services.PrincipalFromContext(c)
+	if err := jwt.service.Logout(c.Request.Context(), principal, req.RefreshToken); err != nil {
+		logging.CtxLogger(c).Error("logout fail", zap.Error(err))
+		OnError(c, err)
+		return
+	}
+	OnSuccess(c, nil)
+}
+
+// JWKS responds public keys to verify tokens in JSON web key set format, for other services
+func (jwt *JWTAuthController) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwt.service.JWKS())
 }
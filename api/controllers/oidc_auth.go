@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/constants"
+	"github.com/dean2032/go-project-layout/services"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// oidcFlowCookie cookie keeping OIDCFlow between login and callback
+	oidcFlowCookie = "oidc_flow"
+	// oidcFlowMaxAge seconds for user to finish sign in at provider
+	oidcFlowMaxAge = 600
+)
+
+// OIDCAuthController sign in by OpenID Connect providers
+type OIDCAuthController struct {
+	service     *services.OIDCService
+	jwtService  *services.JWTAuthService
+	userService *services.UserService
+	// secureCookie whether cookies are sent over https only, as the external url is of https.
+	// the server may be behind a proxy terminating tls so it's not of request
+	secureCookie bool
+}
+
+// NewOIDCAuthController creates new controller
+func NewOIDCAuthController(
+	cfg *config.Config,
+	service *services.OIDCService,
+	jwtService *services.JWTAuthService,
+	userService *services.UserService,
+) *OIDCAuthController {
+	return &OIDCAuthController{
+		service:      service,
+		jwtService:   jwtService,
+		userService:  userService,
+		secureCookie: strings.HasPrefix(strings.ToLower(cfg.OIDC.RedirectBaseURL), "https://"),
+	}
+}
+
+// Login redirects to provider, secrets of the flow are kept in a http only cookie until callback
+func (o *OIDCAuthController) Login(c *gin.Context) {
+	provider := c.Param("provider")
+	url, flow, err := o.service.AuthCodeURL(c.Request.Context(), provider)
+	if err != nil {
+		logging.CtxLogger(c).Info("start oidc sign in fail", zap.String("provider", provider), zap.Error(err))
+		OnError(c, err)
+		return
+	}
+	data, _ := json.Marshal(flow)
+	o.setFlowCookie(c, provider, base64.RawURLEncoding.EncodeToString(data), oidcFlowMaxAge)
+	c.Redirect(http.StatusFound, url)
+}
+
+// Callback links identity asserted by provider to user, and responds tokens of the user
+func (o *OIDCAuthController) Callback(c *gin.Context) {
+	provider := c.Param("provider")
+	flow, err := flowFromCookie(c)
+	// the flow is used once
+	o.setFlowCookie(c, provider, "", -1)
+	if err != nil {
+		OnError(c, err)
+		return
+	}
+	if e := c.Query("error"); e != "" {
+		OnError(c, errors.CodeErrorf(errors.AuthError, "%s: %s", e, c.Query("error_description")))
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Query("state")), []byte(flow.State)) != 1 {
+		OnError(c, errors.CodeErrorf(errors.AuthError, "state mismatch"))
+		return
+	}
+	identity, err := o.service.Callback(c.Request.Context(), provider, c.Query("code"), flow)
+	if err != nil {
+		logging.CtxLogger(c).Info("oidc callback fail", zap.String("provider", provider), zap.Error(err))
+		OnError(c, err)
+		return
+	}
+	txHandle := c.MustGet(constants.DBTransaction).(*gorm.DB)
+	user, err := o.userService.WithTx(txHandle).LinkIdentity(identity)
+	if err != nil {
+		logging.CtxLogger(c).Info("link identity fail", zap.String("provider", provider),
+			zap.String("subject", identity.Subject), zap.Error(err))
+		OnError(c, err)
+		return
+	}
+	tokens, err := o.jwtService.WithTx(txHandle).IssueTokens(user)
+	if err != nil {
+		logging.CtxLogger(c).Error("issue tokens fail", zap.Uint("user_id", user.ID), zap.Error(err))
+		OnError(c, err)
+		return
+	}
+	OnSuccess(c, tokens)
+}
+
+// setFlowCookie sets cookie of flow to the callback path of provider, maxAge < 0 deletes it
+func (o *OIDCAuthController) setFlowCookie(c *gin.Context, provider, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    value,
+		Path:     "/auth/oidc/" + provider + "/callback",
+		MaxAge:   maxAge,
+		Secure:   o.secureCookie,
+		HttpOnly: true,
+		// sent on the top level redirect from provider
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func flowFromCookie(c *gin.Context) (services.OIDCFlow, error) {
+	var flow services.OIDCFlow
+	value, err := c.Cookie(oidcFlowCookie)
+	if err != nil {
+		return flow, errors.CodeErrorf(errors.AuthError, "sign in expired, please try again")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err == nil {
+		err = json.Unmarshal(data, &flow)
+	}
+	if err != nil || flow.State == "" {
+		return flow, errors.CodeErrorf(errors.AuthError, "invalid sign in flow")
+	}
+	return flow, nil
+}
@@ -3,7 +3,7 @@ package controllers
 import (
 	"net/http"
 
-	"github.com/dean2032/go-project-layout/constants"
+	"github.com/dean2032/go-project-layout/repo"
 	"github.com/dean2032/go-project-layout/utils/errors"
 	"github.com/dean2032/go-project-layout/utils/logging"
 	"github.com/gin-gonic/gin"
@@ -14,27 +14,13 @@ type Response struct {
 	Data    interface{} `json:"data"`
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
+	// Pagination is set for listing, Data is the items of page
+	Pagination *repo.Pagination `json:"pagination,omitempty"`
 }
 
 // ApiHandler is a api handler function
 type ApiHandler = func(c *gin.Context) *Response
 
-// OnError make a error response
-func OnError(c *gin.Context, err error) {
-	cause := errors.Cause(err)
-	response := &Response{Code: 100}
-	var codeErr *errors.CodeError
-	if realCodeErr, ok := cause.(*errors.CodeError); ok {
-		codeErr = realCodeErr
-	} else {
-		codeErr = errors.Err2Code(err)
-	}
-	response.Code = codeErr.Code()
-	response.Message = err.Error()
-	c.Set(constants.ErrorCodeGinContextKey, codeErr.Error())
-	setResponse(c, response)
-}
-
 // OnSuccess make a success response
 func OnSuccess(c *gin.Context, data interface{}) {
 	setResponse(c, &Response{
@@ -44,6 +30,46 @@ func OnSuccess(c *gin.Context, data interface{}) {
 	})
 }
 
+// OnList make a success response of a page of listing
+func OnList(c *gin.Context, data interface{}, pagination repo.Pagination) {
+	setResponse(c, &Response{
+		Code:       0,
+		Message:    "OK",
+		Data:       data,
+		Pagination: &pagination,
+	})
+}
+
+// BindJSON binds json body to obj, errors of malformed body and validation are InputError,
+// body beyond the limit of security.max_body_bytes is RequestTooLarge
+func BindJSON(c *gin.Context, obj interface{}) error {
+	return bodyError(c.ShouldBindJSON(obj), "invalid request body")
+}
+
+// ReadBody reads the request body, body beyond the limit of security.max_body_bytes is RequestTooLarge
+func ReadBody(c *gin.Context) ([]byte, error) {
+	body, err := c.GetRawData()
+	return body, bodyError(err, "read body")
+}
+
+// bodyError returns RequestTooLarge if err is of reading body beyond the limit, otherwise InputError of msg
+func bodyError(err error, msg string) error {
+	if maxErr, ok := err.(*http.MaxBytesError); ok {
+		return errors.CodeErrorf(errors.RequestTooLarge, "request body larger than %d bytes", maxErr.Limit)
+	}
+	return errors.CodeWrap(errors.InputError, err, msg)
+}
+
+// BindQuery binds query string to obj, errors are InputError
+func BindQuery(c *gin.Context, obj interface{}) error {
+	return errors.CodeWrap(errors.InputError, c.ShouldBindQuery(obj), "invalid query")
+}
+
+// BindListQuery parses pagination, filters and sorting of query string by spec
+func BindListQuery(c *gin.Context, spec repo.ListSpec) (repo.ListQuery, error) {
+	return repo.ParseListQuery(c.Request.URL.Query(), spec)
+}
+
 func setResponse(c *gin.Context, r *Response) {
 	logger := logging.CtxLogger(c).Sugar()
 	if !c.Writer.Written() {
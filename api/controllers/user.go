@@ -1,16 +1,40 @@
 package controllers
 
 import (
+	"encoding/json"
+	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/dean2032/go-project-layout/constants"
 	"github.com/dean2032/go-project-layout/models"
+	"github.com/dean2032/go-project-layout/repo"
 	"github.com/dean2032/go-project-layout/services"
+	"github.com/dean2032/go-project-layout/utils/errors"
 	"github.com/dean2032/go-project-layout/utils/logging"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+// userListSpec query parameters supported by user listing, e.g.
+// /api/user?name=bob&age_min=18&sort=-created_at&page_size=10
+var userListSpec = repo.ListSpec{
+	SortFields: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"age":        "age",
+		"created_at": "created_at",
+	},
+	DefaultSort: "id",
+	Filters: map[string]repo.FilterSpec{
+		"name":    {Column: "name", Op: repo.OpContains},
+		"email":   {Column: "email", Op: repo.OpEq},
+		"age_min": {Column: "age", Op: repo.OpGte, Kind: repo.FilterInt},
+		"age_max": {Column: "age", Op: repo.OpLte, Kind: repo.FilterInt},
+	},
+}
+
 // UserController data type
 type UserController struct {
 	service *services.UserService
@@ -25,15 +49,12 @@ func NewUserController(userService *services.UserService) *UserController {
 
 // GetOneUser gets one user
 func (u *UserController) GetOneUser(c *gin.Context) {
-	paramID := c.Param("id")
-
-	id, err := strconv.Atoi(paramID)
+	id, err := userID(c)
 	if err != nil {
-		logging.Error(err.Error())
 		OnError(c, err)
 		return
 	}
-	user, err := u.service.GetOneUser(uint(id))
+	user, err := u.service.GetOneUser(id)
 
 	if err != nil {
 		logging.Error(err.Error())
@@ -41,16 +62,24 @@ func (u *UserController) GetOneUser(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", userETag(user))
 	OnSuccess(c, user)
 }
 
-// GetUser gets the user
+// GetUser gets a page of users
 func (u *UserController) GetUser(c *gin.Context) {
-	users, err := u.service.GetAllUser()
+	q, err := BindListQuery(c, userListSpec)
+	if err != nil {
+		OnError(c, err)
+		return
+	}
+	users, pagination, err := u.service.ListUsers(q)
 	if err != nil {
 		logging.Error(err.Error())
+		OnError(c, err)
+		return
 	}
-	OnSuccess(c, users)
+	OnList(c, users, pagination)
 }
 
 // SaveUser saves the user
@@ -58,7 +87,7 @@ func (u *UserController) SaveUser(c *gin.Context) {
 	user := models.User{}
 	txHandle := c.MustGet(constants.DBTransaction).(*gorm.DB)
 
-	if err := c.ShouldBindJSON(&user); err != nil {
+	if err := BindJSON(c, &user); err != nil {
 		logging.Error(err.Error())
 		OnError(c, err)
 		return
@@ -73,23 +102,97 @@ func (u *UserController) SaveUser(c *gin.Context) {
 	OnSuccess(c, nil)
 }
 
-// UpdateUser updates user
+// UpdateUser updates user and responds the updated user.
+// PUT and POST replace the user, PATCH applies JSON merge patch (RFC 7386).
+// The version the change is based on must be set by If-Match header or version field of body,
+// Conflict error is responded if the user has been changed since then.
 func (u *UserController) UpdateUser(c *gin.Context) {
-	OnSuccess(c, nil)
+	id, err := userID(c)
+	if err != nil {
+		OnError(c, err)
+		return
+	}
+	body, err := ReadBody(c)
+	if err != nil {
+		OnError(c, err)
+		return
+	}
+	version, err := expectedVersion(c, body)
+	if err != nil {
+		OnError(c, err)
+		return
+	}
+
+	txHandle := c.MustGet(constants.DBTransaction).(*gorm.DB)
+	service := u.service.WithTx(txHandle)
+	var user models.User
+	if c.Request.Method == http.MethodPatch {
+		user, err = service.PatchUser(id, body, version)
+	} else {
+		if err = json.Unmarshal(body, &user); err != nil {
+			err = errors.CodeWrap(errors.InputError, err, "invalid user")
+		} else {
+			user.ID = id
+			user, err = service.ReplaceUser(user, version)
+		}
+	}
+	if err != nil {
+		logging.CtxLogger(c).Error("update user fail", zap.Uint("id", id), zap.Error(err))
+		OnError(c, err)
+		return
+	}
+
+	c.Header("ETag", userETag(user))
+	OnSuccess(c, user)
+}
+
+// userETag entity tag of user, which is the quoted version
+func userETag(user models.User) string {
+	return strconv.Quote(strconv.FormatUint(uint64(user.Version), 10))
+}
+
+// expectedVersion returns version of If-Match header, or version field of body
+func expectedVersion(c *gin.Context, body []byte) (uint, error) {
+	if match := c.GetHeader("If-Match"); match != "" {
+		v, err := strconv.ParseUint(strings.Trim(strings.TrimPrefix(match, "W/"), `"`), 10, 0)
+		if err != nil {
+			return 0, errors.CodeErrorf(errors.InputError, "invalid If-Match: %s", match)
+		}
+		return uint(v), nil
+	}
+	var versioned struct {
+		Version *uint `json:"version"`
+	}
+	if err := json.Unmarshal(body, &versioned); err != nil {
+		return 0, errors.CodeWrap(errors.InputError, err, "invalid body")
+	}
+	if versioned.Version == nil {
+		return 0, errors.CodeErrorf(errors.InputError, "version is required by If-Match header or version field")
+	}
+	return *versioned.Version, nil
+}
+
+// userID parses positive user id of path, InputError is returned if invalid
+func userID(c *gin.Context) (uint, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		return 0, errors.CodeWrap(errors.InputError, err, "invalid user id")
+	}
+	if id == 0 {
+		return 0, errors.CodeErrorf(errors.InputError, "invalid user id 0")
+	}
+	return uint(id), nil
 }
 
 // DeleteUser deletes user
 func (u *UserController) DeleteUser(c *gin.Context) {
-	paramID := c.Param("id")
-
-	id, err := strconv.Atoi(paramID)
+	id, err := userID(c)
 	if err != nil {
-		logging.Error(err.Error())
 		OnError(c, err)
 		return
 	}
 
-	if err := u.service.DeleteUser(uint(id)); err != nil {
+	if err := u.service.DeleteUser(id); err != nil {
 		logging.Error(err.Error())
 		OnError(c, err)
 		return
@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/gin-gonic/gin"
+)
+
+func TestUserID(t *testing.T) {
+	for param, want := range map[string]uint{"7": 7, "0": 0, "-1": 0, "abc": 0, "99999999999999999999": 0} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Params = gin.Params{{Key: "id", Value: param}}
+		id, err := userID(c)
+		if want == 0 && !errors.IsCodeErrorEqual(err, errors.InputError) {
+			t.Errorf("%s should be rejected, got %d %v", param, id, err)
+		}
+		if want != 0 && (err != nil || id != want) {
+			t.Errorf("%s: got %d %v", param, id, err)
+		}
+	}
+}
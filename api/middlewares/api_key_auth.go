@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"github.com/dean2032/go-project-layout/api/controllers"
+	"github.com/dean2032/go-project-layout/services"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// APIKeyAuthMiddleware middleware for api key authentication
+type APIKeyAuthMiddleware struct {
+	service *services.APIKeyService
+}
+
+// NewAPIKeyAuthMiddleware creates new api key auth middleware
+func NewAPIKeyAuthMiddleware(
+	service *services.APIKeyService,
+) *APIKeyAuthMiddleware {
+	return &APIKeyAuthMiddleware{
+		service: service,
+	}
+}
+
+// Setup sets up api key auth middleware
+func (m *APIKeyAuthMiddleware) Setup() {}
+
+// Handler handles middleware functionality, requests without api key are rejected
+func (m *APIKeyAuthMiddleware) Handler() gin.HandlerFunc {
+	return m.HandlerOr(func(c *gin.Context) {
+		unauthorized(c, errors.CodeErrorf(errors.AuthError, "api key required"))
+	})
+}
+
+// HandlerOr authenticates requests with api key header by the key,
+// and hands requests without it to fallback, e.g. JWTAuthMiddleware.Handler to accept either credential
+func (m *APIKeyAuthMiddleware) HandlerOr(fallback gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(services.APIKeyHeader)
+		if key == "" {
+			fallback(c)
+			return
+		}
+		principal, err := m.service.Authenticate(c.Request.Context(), key)
+		if err != nil {
+			logging.CtxLogger(c).Info("authenticate api key fail", zap.Error(err))
+			controllers.OnError(c, err)
+			return
+		}
+		services.SetPrincipal(c, principal)
+		logging.AddCtxFields(c, zap.Uint(logging.APIKeyIDKeyName, principal.APIKeyID))
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/dean2032/go-project-layout/api/controllers"
+	"github.com/dean2032/go-project-layout/services"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequireScopes creates middleware allowing principal with all of scopes,
+// it's used per route or group after JWTAuthMiddleware
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	return authorize(func(p *services.Principal) error {
+		for _, scope := range scopes {
+			if !p.HasScope(scope) {
+				return errors.CodeErrorf(errors.Forbidden, "scope %s is required", scope)
+			}
+		}
+		return nil
+	})
+}
+
+// RequireRoles creates middleware allowing principal with any of roles,
+// it's used per route or group after JWTAuthMiddleware
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return authorize(func(p *services.Principal) error {
+		for _, role := range roles {
+			if p.HasRole(role) {
+				return nil
+			}
+		}
+		return errors.CodeErrorf(errors.Forbidden, "one of roles %s is required", strings.Join(roles, ", "))
+	})
+}
+
+func authorize(check func(p *services.Principal) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := services.PrincipalFromContext(c)
+		if !ok {
+			unauthorized(c, errors.CodeErrorf(errors.AuthError, "you are not authorized"))
+			return
+		}
+		if err := check(principal); err != nil {
+			logging.CtxLogger(c).Info("access denied", zap.Error(err))
+			controllers.OnError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
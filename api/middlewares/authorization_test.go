@@ -0,0 +1,57 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dean2032/go-project-layout/services"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthorization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	principal := &services.Principal{UserID: 1, Roles: []string{"member"}, Scopes: []string{"users:read"}}
+	cases := []struct {
+		name      string
+		principal *services.Principal
+		handler   gin.HandlerFunc
+		want      int
+	}{
+		{"unauthenticated", nil, RequireScopes("users:read"), http.StatusUnauthorized},
+		{"scope", principal, RequireScopes("users:read"), http.StatusOK},
+		{"missing scope", principal, RequireScopes("users:read", "users:write"), http.StatusForbidden},
+		{"any role", principal, RequireRoles("admin", "member"), http.StatusOK},
+		{"missing role", principal, RequireRoles("admin"), http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		r := gin.New()
+		r.GET("/", func(c *gin.Context) {
+			if tc.principal != nil {
+				services.SetPrincipal(c, tc.principal)
+			}
+		}, tc.handler, func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != tc.want {
+			t.Error(tc.name, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAPIKeyFallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := NewAPIKeyAuthMiddleware(nil)
+	r := gin.New()
+	r.GET("/or", m.HandlerOr(func(c *gin.Context) { c.Status(http.StatusTeapot) }))
+	r.GET("/only", m.Handler())
+	for path, want := range map[string]int{"/or": http.StatusTeapot, "/only": http.StatusUnauthorized} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Error(path, w.Code)
+		}
+	}
+}
@@ -1,8 +1,13 @@
 package middlewares
 
 import (
+	"sort"
+	"strings"
+	"sync/atomic"
+
 	"github.com/dean2032/go-project-layout/config"
 	"github.com/dean2032/go-project-layout/utils/logging"
+	"github.com/gin-gonic/gin"
 	cors "github.com/rs/cors/wrapper/gin"
 )
 
@@ -10,25 +15,80 @@ import (
 type CorsMiddleware struct {
 	handler *RequestHandler
 	cfg     *config.Config
+	// policies holds *corsPolicies of config, rebuilt on config reload
+	policies atomic.Value
+}
+
+// corsPolicies cors handlers of the policy and of route groups
+type corsPolicies struct {
+	policy gin.HandlerFunc
+	// groups sorted by longer prefix first
+	groups []corsGroup
+}
+
+type corsGroup struct {
+	prefix  string
+	handler gin.HandlerFunc
 }
 
 // NewCorsMiddleware creates new cors middleware
-func NewCorsMiddleware(handler *RequestHandler, cfg *config.Config) *CorsMiddleware {
-	return &CorsMiddleware{
+func NewCorsMiddleware(handler *RequestHandler, cfg *config.Config, watcher *config.Watcher) *CorsMiddleware {
+	m := &CorsMiddleware{
 		handler: handler,
 		cfg:     cfg,
 	}
+	m.setPolicies(cfg.Cors)
+	watcher.Subscribe(func(prev, cur *config.Config) {
+		m.setPolicies(cur.Cors)
+	})
+	return m
+}
+
+func (m *CorsMiddleware) setPolicies(c config.CorsConfig) {
+	policies := &corsPolicies{policy: m.newCors(c)}
+	for prefix := range c.Groups {
+		policies.groups = append(policies.groups, corsGroup{prefix: prefix, handler: m.newCors(c.Group(prefix))})
+	}
+	sort.Slice(policies.groups, func(i, j int) bool {
+		return len(policies.groups[i].prefix) > len(policies.groups[j].prefix)
+	})
+	m.policies.Store(policies)
+}
+
+func (m *CorsMiddleware) newCors(policy config.CorsConfig) gin.HandlerFunc {
+	options := cors.Options{
+		AllowedOrigins:   policy.AllowedOrigins,
+		AllowedMethods:   policy.AllowedMethods,
+		AllowedHeaders:   policy.AllowedHeaders,
+		ExposedHeaders:   policy.ExposedHeaders,
+		MaxAge:           policy.MaxAge,
+		AllowCredentials: policy.AllowCredentials,
+		Debug:            m.cfg.Debug,
+	}
+	if len(policy.AllowedOrigins) == 0 {
+		// rs/cors allows all origins if none given
+		options.AllowOriginFunc = func(string) bool { return false }
+	}
+	return cors.New(options)
+}
+
+// handle applies policy of the group of longest prefix of request path, preflight requests
+// have no routes so groups are matched by path instead of gin route groups
+func (m *CorsMiddleware) handle(c *gin.Context) {
+	policies := m.policies.Load().(*corsPolicies)
+	path := c.Request.URL.Path
+	for _, g := range policies.groups {
+		if path == g.prefix || strings.HasPrefix(path, strings.TrimSuffix(g.prefix, "/")+"/") {
+			g.handler(c)
+			return
+		}
+	}
+	policies.policy(c)
 }
 
 // Setup sets up cors middleware
 func (m *CorsMiddleware) Setup() {
 	logging.Info("Setting up cors middleware")
 
-	m.handler.Gin.Use(cors.New(cors.Options{
-		AllowCredentials: true,
-		AllowOriginFunc:  func(origin string) bool { return true },
-		AllowedHeaders:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "HEAD", "OPTIONS"},
-		Debug:            m.cfg.Debug,
-	}))
+	m.handler.Gin.Use(m.handle)
 }
@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx/fxtest"
+)
+
+func TestCors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.DefaultConfig()
+	cfg.Cors.AllowedOrigins = []string{"https://app.example.com", "https://*.example.org"}
+	cfg.Cors.AllowCredentials = true
+	noCredentials := false
+	cfg.Cors.Groups = map[string]config.CorsOverride{
+		"/public": {AllowedOrigins: []string{"*"}, AllowCredentials: &noCredentials},
+	}
+	handler := &RequestHandler{Gin: gin.New()}
+	handler.Gin.HandleMethodNotAllowed = true
+	m := NewCorsMiddleware(handler, cfg, config.NewWatcher(fxtest.NewLifecycle(t), cfg))
+	m.Setup()
+	handler.Gin.GET("/api/user", func(c *gin.Context) { c.Status(http.StatusOK) })
+	handler.Gin.GET("/public/file", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		name            string
+		method          string
+		path            string
+		origin          string
+		wantOrigin      string
+		wantCredentials string
+	}{
+		{"preflight of DELETE", http.MethodOptions, "/api/user", "https://app.example.com", "https://app.example.com", "true"},
+		{"wildcard origin", http.MethodGet, "/api/user", "https://a.example.org", "https://a.example.org", "true"},
+		{"origin not allowed", http.MethodGet, "/api/user", "https://evil.com", "", ""},
+		{"suffix of wildcard not matched", http.MethodGet, "/api/user", "https://a.example.org.evil.com", "", ""},
+		{"group allows all without credentials", http.MethodGet, "/public/file", "https://evil.com", "*", ""},
+		{"group prefix on segment boundary", http.MethodGet, "/publicity", "https://evil.com", "", ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("Origin", tc.origin)
+		if tc.method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+		}
+		w := httptest.NewRecorder()
+		handler.Gin.ServeHTTP(w, req)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != tc.wantOrigin {
+			t.Errorf("%s: allow origin got %q, want %q", tc.name, got, tc.wantOrigin)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tc.wantCredentials {
+			t.Errorf("%s: allow credentials got %q, want %q", tc.name, got, tc.wantCredentials)
+		}
+		if tc.method == http.MethodOptions {
+			if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Methods") != http.MethodDelete ||
+				w.Header().Get("Access-Control-Max-Age") != "600" {
+				t.Errorf("%s: unexpected preflight response %d %v", tc.name, w.Code, w.Header())
+			}
+		}
+	}
+
+	// origins are replaced on config reload
+	cfg.Cors.AllowedOrigins = []string{"https://new.example.com"}
+	m.setPolicies(cfg.Cors)
+	req := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w := httptest.NewRecorder()
+	handler.Gin.ServeHTTP(w, req)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Error("removed origin should not be allowed", got)
+	}
+}
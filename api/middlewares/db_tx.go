@@ -41,30 +41,33 @@ func (m *DatabaseTx) Setup() {
 	logging.Info("setting up database transaction middleware")
 
 	m.handler.Gin.Use(func(c *gin.Context) {
-		txHandle := m.db.DB.Begin()
+		// statements of the request are traced as children of its span
+		txHandle := m.db.DB.WithContext(c.Request.Context()).Begin()
 		logging.Info("beginning database transaction")
 
 		defer func() {
 			if r := recover(); r != nil {
-				txHandle.Rollback()
+				_ = repo.RollbackTx(txHandle)
+				panic(r)
 			}
 		}()
 
 		c.Set(constants.DBTransaction, txHandle)
 		c.Next()
 
-		// rollback transaction on server errors
-		if c.Writer.Status() == http.StatusInternalServerError {
-			logging.Info("rolling back transaction due to status code: 500")
-			txHandle.Rollback()
-		}
-
 		// commit transaction on success status
 		if statusInList(c.Writer.Status(), []int{http.StatusOK, http.StatusCreated}) {
 			logging.Info("committing transactions")
-			if err := txHandle.Commit().Error; err != nil {
+			if err := repo.CommitTx(txHandle); err != nil {
 				logging.Errorf("tx commit error: %s", err.Error())
 			}
+			return
+		}
+
+		// rollback transaction on other status
+		logging.Infof("rolling back transaction due to status code: %d", c.Writer.Status())
+		if err := repo.RollbackTx(txHandle); err != nil {
+			logging.Errorf("tx rollback error: %s", err.Error())
 		}
 	})
 }
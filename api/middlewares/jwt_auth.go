@@ -1,12 +1,14 @@
 package middlewares
 
 import (
-	"net/http"
 	"strings"
 
+	"github.com/dean2032/go-project-layout/api/controllers"
 	"github.com/dean2032/go-project-layout/services"
+	"github.com/dean2032/go-project-layout/utils/errors"
 	"github.com/dean2032/go-project-layout/utils/logging"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // JWTAuthMiddleware middleware for jwt authentication
@@ -31,23 +33,25 @@ func (m *JWTAuthMiddleware) Handler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.Request.Header.Get("Authorization")
 		t := strings.Split(authHeader, " ")
-		if len(t) == 2 {
-			authToken := t[1]
-			authorized, err := m.service.Authorize(authToken)
-			if authorized {
-				c.Next()
-				return
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": err.Error(),
-			})
-			logging.Error(err.Error())
-			c.Abort()
+		if len(t) != 2 {
+			unauthorized(c, errors.CodeErrorf(errors.AuthError, "you are not authorized"))
 			return
 		}
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "you are not authorized",
-		})
-		c.Abort()
+		claims, err := m.service.Authorize(c.Request.Context(), t[1])
+		if err != nil {
+			logging.CtxLogger(c).Info("authorize fail", zap.Error(err))
+			unauthorized(c, err)
+			return
+		}
+		principal := claims.Principal()
+		services.SetPrincipal(c, principal)
+		logging.AddCtxFields(c, zap.Uint(logging.UserIDKeyName, principal.UserID))
+		c.Next()
 	}
 }
+
+// unauthorized responds error of authentication with challenge of RFC 6750
+func unauthorized(c *gin.Context, err error) {
+	c.Header("WWW-Authenticate", services.TokenTypeBearer)
+	controllers.OnError(c, err)
+}
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dean2032/go-project-layout/constants"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute route label of requests not matching any route, so that paths don't blow up cardinality
+const unmatchedRoute = "unmatched"
+
+// otherMethod method label of methods not in standardMethods, clients may send any method
+const otherMethod = "OTHER"
+
+// standardMethods methods of RFC 9110 and PATCH
+var standardMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodConnect: true,
+	http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// methodLabel returns method label of request method
+func methodLabel(method string) string {
+	if standardMethods[method] {
+		return method
+	}
+	return otherMethod
+}
+
+// httpMetrics records requests by route template and codes of error responses
+func httpMetrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		done := m.RequestStarted()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		done(route, methodLabel(c.Request.Method), c.Writer.Status(), time.Since(start).Seconds())
+		if codeErr, ok := c.Value(constants.ErrorCodeGinContextKey).(*errors.CodeError); ok {
+			m.Error(codeErr.Code())
+		}
+	}
+}
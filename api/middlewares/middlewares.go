@@ -6,7 +6,10 @@ import "go.uber.org/fx"
 var Module = fx.Options(
 	fx.Provide(NewCorsMiddleware),
 	fx.Provide(NewJWTAuthMiddleware),
+	fx.Provide(NewAPIKeyAuthMiddleware),
 	fx.Provide(NewDatabaseTx),
+	fx.Provide(NewRateLimitMiddleware),
+	fx.Provide(NewSecurityMiddleware),
 	fx.Provide(NewMiddlewares),
 	fx.Provide(NewRequestHandler),
 )
@@ -23,10 +26,13 @@ type Middlewares []IMiddleware
 // Register the middleware that should be applied directly (globally)
 func NewMiddlewares(
 	corsMiddleware *CorsMiddleware,
+	rateLimitMiddleware *RateLimitMiddleware,
 	dbTxMiddleware *DatabaseTx,
 ) Middlewares {
+	// rate limit before database transaction, cors before rate limit for 429 responses to be readable by browsers
 	return Middlewares{
 		corsMiddleware,
+		rateLimitMiddleware,
 		dbTxMiddleware,
 	}
 }
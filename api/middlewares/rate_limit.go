@@ -0,0 +1,112 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/dean2032/go-project-layout/api/controllers"
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/services"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"github.com/dean2032/go-project-layout/utils/ratelimit"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// rate limit headers of draft-ietf-httpapi-ratelimit-headers
+const (
+	rateLimitLimitHeader     = "RateLimit-Limit"
+	rateLimitRemainingHeader = "RateLimit-Remaining"
+	rateLimitResetHeader     = "RateLimit-Reset"
+	rateLimitPolicyHeader    = "RateLimit-Policy"
+)
+
+// RateLimitMiddleware middleware limiting requests of route groups by client,
+// all requests are limited by client ip of global group before other middlewares
+type RateLimitMiddleware struct {
+	handler *RequestHandler
+	limiter ratelimit.Limiter
+	// limits holds map[string]config.RateLimit of groups, updated on config reload
+	limits atomic.Value
+}
+
+// NewRateLimitMiddleware creates new rate limit middleware, limiter is nil if rate limiting is disabled
+func NewRateLimitMiddleware(
+	handler *RequestHandler,
+	cfg *config.Config,
+	watcher *config.Watcher,
+	limiter ratelimit.Limiter,
+) *RateLimitMiddleware {
+	m := &RateLimitMiddleware{
+		handler: handler,
+		limiter: limiter,
+	}
+	m.limits.Store(cfg.RateLimit.Limits)
+	watcher.Subscribe(func(prev, cur *config.Config) {
+		m.limits.Store(cur.RateLimit.Limits)
+	})
+	return m
+}
+
+// Setup sets up rate limit middleware of global group, it's installed before database transaction
+// so that requests beyond the limit don't take connections of the pool
+func (m *RateLimitMiddleware) Setup() {
+	logging.Info("setting up rate limit middleware")
+	m.handler.Gin.Use(m.Handler(config.RateLimitGlobal))
+}
+
+// Handler limits requests of group by limit of rate_limit.limits.<group>, install it after authentication
+// for authenticated users and api keys to be limited by themselves instead of client ip.
+// Groups other than global run after the database transaction begins, and after api key lookup
+// if installed after authentication, so requests beyond their limit still cost those.
+// Requests beyond the limit are rejected with errors.TooManyRequests and Retry-After header.
+func (m *RateLimitMiddleware) Handler(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := m.limits.Load().(map[string]config.RateLimit)[group]
+		if m.limiter == nil || limit.Requests <= 0 {
+			c.Next()
+			return
+		}
+		result, err := m.limiter.Allow(c.Request.Context(), group+":"+clientKey(c),
+			ratelimit.Limit{Requests: limit.Requests, Period: limit.PeriodDuration()})
+		if err != nil {
+			// let requests pass rather than failing all of them when backend is down
+			logging.CtxLogger(c).Warn("rate limit fail", zap.String("group", group), zap.Error(err))
+			c.Next()
+			return
+		}
+		h := c.Writer.Header()
+		h.Set(rateLimitLimitHeader, strconv.Itoa(limit.Requests))
+		h.Set(rateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+		h.Set(rateLimitResetHeader, strconv.Itoa(seconds(result.Reset)))
+		h.Set(rateLimitPolicyHeader, fmt.Sprintf("%d;w=%d", limit.Requests, limit.Period))
+		if !result.Allowed {
+			retryAfter := seconds(result.RetryAfter)
+			h.Set("Retry-After", strconv.Itoa(retryAfter))
+			controllers.OnError(c, errors.CodeErrorf(errors.TooManyRequests,
+				"rate limit of %s exceeded, retry after %d seconds", group, retryAfter))
+			return
+		}
+		c.Next()
+	}
+}
+
+// clientKey identifies client of request by principal, or by ip if it's not authenticated
+func clientKey(c *gin.Context) string {
+	if p, ok := services.PrincipalFromContext(c); ok {
+		if p.APIKeyID != 0 {
+			return "apikey:" + strconv.FormatUint(uint64(p.APIKeyID), 10)
+		}
+		return "user:" + strconv.FormatUint(uint64(p.UserID), 10)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// seconds rounds d up to seconds, at least 1 for a positive d
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
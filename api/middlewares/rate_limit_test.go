@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/services"
+	"github.com/dean2032/go-project-layout/utils/metrics"
+	"github.com/dean2032/go-project-layout/utils/ratelimit"
+	"github.com/gin-gonic/gin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/fx/fxtest"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.DefaultConfig()
+	cfg.RateLimit.Limits = map[string]config.RateLimit{"api": {Requests: 2, Period: 60}}
+	m := NewRateLimitMiddleware(nil, cfg, config.NewWatcher(fxtest.NewLifecycle(t), cfg), ratelimit.NewMemoryLimiter())
+	r := gin.New()
+	r.GET("/api", func(c *gin.Context) {
+		if c.Query("user") != "" {
+			services.SetPrincipal(c, &services.Principal{UserID: 1})
+		}
+	}, m.Handler("api"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	r.GET("/unlimited", m.Handler("other"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	for _, remaining := range []string{"1", "0"} {
+		w := get("/api")
+		if w.Code != http.StatusOK || w.Header().Get(rateLimitRemainingHeader) != remaining {
+			t.Error("request within limit", w.Code, w.Header())
+		}
+	}
+	w := get("/api")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" {
+		t.Error("request beyond limit", w.Code, w.Header())
+	}
+	if w.Header().Get(rateLimitLimitHeader) != "2" || w.Header().Get(rateLimitPolicyHeader) != "2;w=60" ||
+		w.Header().Get(rateLimitResetHeader) != "60" {
+		t.Error("unexpected rate limit headers", w.Header())
+	}
+	// authenticated user has its own limit besides the client ip
+	if w := get("/api?user=1"); w.Code != http.StatusOK {
+		t.Error("user should be limited separately", w.Code)
+	}
+	for i := 0; i < 3; i++ {
+		if w := get("/unlimited"); w.Code != http.StatusOK || w.Header().Get(rateLimitLimitHeader) != "" {
+			t.Error("group without limit should not be limited", w.Code, w.Header())
+		}
+	}
+}
+
+func TestRateLimitGlobal(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.RateLimit.Limits = map[string]config.RateLimit{config.RateLimitGlobal: {Requests: 1, Period: 60}}
+	watcher := config.NewWatcher(fxtest.NewLifecycle(t), cfg)
+	h := NewRequestHandler(cfg, watcher, metrics.New(), sdktrace.NewTracerProvider())
+	NewRateLimitMiddleware(h, cfg, watcher, ratelimit.NewMemoryLimiter()).Setup()
+	// stands for database transaction of later middlewares
+	began := 0
+	h.Gin.Use(func(c *gin.Context) { began++ })
+	h.Gin.GET("/user", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, status := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		h.Gin.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user", nil))
+		if w.Code != status {
+			t.Error("unexpected status", w.Code, status)
+		}
+	}
+	if began != 1 {
+		t.Error("requests beyond global limit should not reach later middlewares", began)
+	}
+}
@@ -2,15 +2,18 @@ package middlewares
 
 import (
 	"fmt"
-	"net/http"
 	"runtime/debug"
+	"sync/atomic"
 	"time"
 
+	"github.com/dean2032/go-project-layout/api/controllers"
 	"github.com/dean2032/go-project-layout/config"
-	"github.com/dean2032/go-project-layout/constants"
 	"github.com/dean2032/go-project-layout/utils/errors"
 	"github.com/dean2032/go-project-layout/utils/logging"
+	"github.com/dean2032/go-project-layout/utils/metrics"
+	"github.com/dean2032/go-project-layout/utils/tracing"
 	"github.com/gin-gonic/gin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.uber.org/zap"
 )
 
@@ -20,24 +23,53 @@ type RequestHandler struct {
 }
 
 // NewRequestHandler creates a new request handler
-func NewRequestHandler(cfg *config.Config) *RequestHandler {
+func NewRequestHandler(
+	cfg *config.Config,
+	watcher *config.Watcher,
+	m *metrics.Metrics,
+	tp *sdktrace.TracerProvider,
+) *RequestHandler {
 	if !cfg.Debug {
 		gin.SetMode(gin.ReleaseMode)
 	}
+	var slowThreshold atomic.Int64
+	slowThreshold.Store(int64(cfg.SlowDuration()))
+	watcher.Subscribe(func(prev, cur *config.Config) {
+		slowThreshold.Store(int64(cur.SlowDuration()))
+	})
+	controllers.ConfigureErrors(cfg.Errors)
+	watcher.Subscribe(func(prev, cur *config.Config) {
+		controllers.ConfigureErrors(cur.Errors)
+	})
 	app := gin.New()
+	// X-Forwarded-For and X-Real-IP are only trusted from the proxies, so that client ip can't be spoofed
+	if err := app.SetTrustedProxies(cfg.Security.TrustedProxies); err != nil {
+		logging.Panic(err.Error())
+	}
+	// before logger, trace id of logs is the one of request span
+	app.Use(tracing.Middleware(tp))
 	app.Use(logging.GinLoggerWithConfig(logging.GinLoggerConfig{
-		SkipPaths:     []string{"/"},
-		EnableDetails: cfg.Debug,
-		SlowThreshold: 10 * time.Second,
+		EnableDetails:     cfg.Debug,
+		SlowThreshold:     10 * time.Second,
+		SlowThresholdFunc: func() time.Duration { return time.Duration(slowThreshold.Load()) },
 	}))
+	app.Use(httpMetrics(m))
+	if cfg.Security.Enabled {
+		app.Use(securityHeaders(cfg.Security))
+	}
 	app.Use(globalPanicHandler())
-	app.NoMethod(handleNotFound)
+	app.HandleMethodNotAllowed = true
+	app.NoMethod(handleMethodNotAllowed)
 	app.NoRoute(handleNotFound)
 	return &RequestHandler{Gin: app}
 }
 
 func handleNotFound(c *gin.Context) {
-	c.JSON(404, gin.H{"code": 404, "message": "not found"})
+	controllers.OnError(c, errors.CodeErrorf(errors.NotFound, "%s not found", c.Request.URL.Path))
+}
+
+func handleMethodNotAllowed(c *gin.Context) {
+	controllers.OnError(c, errors.CodeErrorf(errors.MethodNotAllowed, "method %s not allowed", c.Request.Method))
 }
 
 func globalPanicHandler() gin.HandlerFunc {
@@ -46,15 +78,11 @@ func globalPanicHandler() gin.HandlerFunc {
 			if err := recover(); err != nil {
 				stack := debug.Stack()
 				logging.CtxLogger(c).Error(fmt.Sprintf("panic: %v", err), zap.String("stack", string(stack)))
-				// do this when response content not set
-				if !c.Writer.Written() {
-					response := gin.H{}
-					if e, ok := err.(error); ok {
-						response = parseError(c, e)
-					} else {
-						response = parseError(c, fmt.Errorf("%s", err))
-					}
-					c.JSON(http.StatusOK, response)
+				// panics of CodeError keep their code, others are UnknownError
+				if e, ok := err.(error); ok {
+					controllers.OnError(c, e)
+				} else {
+					controllers.OnError(c, fmt.Errorf("%v", err))
 				}
 				return
 			}
@@ -62,18 +90,3 @@ func globalPanicHandler() gin.HandlerFunc {
 		c.Next()
 	}
 }
-
-func parseError(c *gin.Context, err error) gin.H {
-	cause := errors.Cause(err)
-	resp := gin.H{"code": 100, "message": "Unknown error"}
-	var codeErr *errors.CodeError
-	if realCodeErr, ok := cause.(*errors.CodeError); ok {
-		codeErr = realCodeErr
-	} else {
-		codeErr = errors.Err2Code(err)
-	}
-	resp["code"] = codeErr.Code()
-	resp["message"] = err.Error()
-	c.Set(constants.ErrorCodeGinContextKey, codeErr.Error())
-	return resp
-}
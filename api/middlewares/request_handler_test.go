@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dean2032/go-project-layout/api/controllers"
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/metrics"
+	"github.com/gin-gonic/gin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/fx/fxtest"
+)
+
+func TestRequestHandlerErrors(t *testing.T) {
+	cfg := config.DefaultConfig()
+	h := NewRequestHandler(cfg, config.NewWatcher(fxtest.NewLifecycle(t), cfg), metrics.New(), sdktrace.NewTracerProvider())
+	h.Gin.GET("/panic", func(c *gin.Context) { panic("boom") })
+	h.Gin.GET("/panic/code", func(c *gin.Context) { panic(errors.CodeErrorf(errors.Conflict, "conflict")) })
+
+	cases := []struct {
+		method, path string
+		status, code int
+	}{
+		{http.MethodGet, "/missing", http.StatusNotFound, errors.NotFound.Code()},
+		{http.MethodPost, "/panic", http.StatusMethodNotAllowed, errors.MethodNotAllowed.Code()},
+		{http.MethodGet, "/panic", http.StatusInternalServerError, errors.UnknownError.Code()},
+		{http.MethodGet, "/panic/code", http.StatusConflict, errors.Conflict.Code()},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		h.Gin.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		var resp controllers.Response
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != tc.status || resp.Code != tc.code {
+			t.Error(tc.method, tc.path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRequestHandlerMetricsMethod(t *testing.T) {
+	cfg := config.DefaultConfig()
+	m := metrics.New()
+	h := NewRequestHandler(cfg, config.NewWatcher(fxtest.NewLifecycle(t), cfg), m, sdktrace.NewTracerProvider())
+	h.Gin.GET("/user", func(c *gin.Context) { c.Status(http.StatusOK) })
+	for _, method := range []string{http.MethodGet, "FOO", "BAR"} {
+		h.Gin.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/user", nil))
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{`method="GET",route="/user"`, `method="OTHER",route="unmatched",status="405"`} {
+		if !strings.Contains(body, want) {
+			t.Error("metrics should contain", want)
+		}
+	}
+	if strings.Contains(body, `method="FOO"`) {
+		t.Error("non standard methods should be labeled OTHER")
+	}
+}
@@ -0,0 +1,84 @@
+package middlewares
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/dean2032/go-project-layout/api/controllers"
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityMiddleware hardening of json routes, it does nothing unless security.enabled
+type SecurityMiddleware struct {
+	cfg *config.Config
+}
+
+// NewSecurityMiddleware creates new security middleware
+func NewSecurityMiddleware(cfg *config.Config) *SecurityMiddleware {
+	return &SecurityMiddleware{
+		cfg: cfg,
+	}
+}
+
+// Setup sets up security middleware, headers and body limit are set up by NewRequestHandler for all servers
+func (m *SecurityMiddleware) Setup() {}
+
+// JSONOnly rejects requests with body of content types other than application/json and application/*+json
+func (m *SecurityMiddleware) JSONOnly() gin.HandlerFunc {
+	if !m.cfg.Security.Enabled {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	return func(c *gin.Context) {
+		if c.Request.ContentLength == 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		contentType := c.GetHeader("Content-Type")
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+			controllers.OnError(c, errors.CodeErrorf(errors.UnsupportedMediaType,
+				"content type %q not supported, use application/json", contentType))
+			return
+		}
+		c.Next()
+	}
+}
+
+// securityHeaders sets security headers of cfg to responses and limits request body to cfg.MaxBodyBytes
+func securityHeaders(cfg config.SecurityConfig) gin.HandlerFunc {
+	headers := map[string]string{"X-Content-Type-Options": "nosniff"}
+	if cfg.HSTSMaxAge > 0 {
+		headers["Strict-Transport-Security"] = fmt.Sprintf("max-age=%d", cfg.HSTSMaxAge)
+	}
+	if cfg.ContentSecurityPolicy != "" {
+		headers["Content-Security-Policy"] = cfg.ContentSecurityPolicy
+	}
+	if cfg.FrameOptions != "" {
+		headers["X-Frame-Options"] = cfg.FrameOptions
+	}
+	if cfg.ReferrerPolicy != "" {
+		headers["Referrer-Policy"] = cfg.ReferrerPolicy
+	}
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		for k, v := range headers {
+			h.Set(k, v)
+		}
+		if cfg.MaxBodyBytes > 0 && c.Request.Body != nil {
+			if c.Request.ContentLength > cfg.MaxBodyBytes {
+				controllers.OnError(c, errors.CodeErrorf(errors.RequestTooLarge,
+					"request body larger than %d bytes", cfg.MaxBodyBytes))
+				return
+			}
+			// chunked bodies fail on reading beyond the limit
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, cfg.MaxBodyBytes)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,96 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dean2032/go-project-layout/api/controllers"
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/metrics"
+	"github.com/gin-gonic/gin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/fx/fxtest"
+)
+
+func TestSecurity(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Security.Enabled = true
+	cfg.Security.MaxBodyBytes = 16
+	cfg.Security.TrustedProxies = []string{"10.0.0.0/8"}
+	h := NewRequestHandler(cfg, config.NewWatcher(fxtest.NewLifecycle(t), cfg), metrics.New(), sdktrace.NewTracerProvider())
+	h.Gin.POST("/json", NewSecurityMiddleware(cfg).JSONOnly(), func(c *gin.Context) {
+		var body map[string]interface{}
+		if err := controllers.BindJSON(c, &body); err != nil {
+			controllers.OnError(c, err)
+			return
+		}
+		controllers.OnSuccess(c, c.ClientIP())
+	})
+	h.Gin.PATCH("/json", NewSecurityMiddleware(cfg).JSONOnly(), func(c *gin.Context) {
+		body, err := controllers.ReadBody(c)
+		if err != nil {
+			controllers.OnError(c, err)
+			return
+		}
+		controllers.OnSuccess(c, len(body))
+	})
+	send := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.Gin.ServeHTTP(w, req)
+		return w
+	}
+	post := func(contentType string, body io.Reader) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/json", body)
+		req.Header.Set("Content-Type", contentType)
+		return req
+	}
+
+	w := send(post("application/json; charset=utf-8", strings.NewReader(`{"a": 1}`)))
+	if w.Code != http.StatusOK {
+		t.Fatal("json body should be accepted", w.Code, w.Body.String())
+	}
+	for k, v := range map[string]string{
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Strict-Transport-Security": "max-age=15552000",
+		"Referrer-Policy":           "strict-origin-when-cross-origin",
+		"Content-Security-Policy":   cfg.Security.ContentSecurityPolicy,
+	} {
+		if got := w.Header().Get(k); got != v {
+			t.Errorf("%s: got %q, want %q", k, got, v)
+		}
+	}
+	if w := send(post("application/merge-patch+json", strings.NewReader(`{"a": 1}`))); w.Code != http.StatusOK {
+		t.Error("json suffix should be accepted", w.Code)
+	}
+	if w := send(post("text/plain", strings.NewReader(`{"a": 1}`))); w.Code != http.StatusUnsupportedMediaType {
+		t.Error("text body should be rejected", w.Code)
+	}
+	large := `{"a": "` + strings.Repeat("x", 32) + `"}`
+	if w := send(post("application/json", strings.NewReader(large))); w.Code != http.StatusRequestEntityTooLarge {
+		t.Error("body larger than limit should be rejected", w.Code)
+	}
+	chunked := post("application/json", io.NopCloser(strings.NewReader(large)))
+	chunked.ContentLength = -1
+	if w := send(chunked); w.Code != http.StatusRequestEntityTooLarge {
+		t.Error("chunked body larger than limit should be rejected", w.Code, w.Body.String())
+	}
+	patch := httptest.NewRequest(http.MethodPatch, "/json", io.NopCloser(strings.NewReader(large)))
+	patch.Header.Set("Content-Type", "application/merge-patch+json")
+	patch.ContentLength = -1
+	if w := send(patch); w.Code != http.StatusRequestEntityTooLarge {
+		t.Error("chunked raw body larger than limit should be rejected", w.Code, w.Body.String())
+	}
+
+	for remote, want := range map[string]string{"10.1.2.3:1234": "1.2.3.4", "8.8.8.8:1234": "8.8.8.8"} {
+		req := post("application/json", strings.NewReader(`{}`))
+		req.RemoteAddr = remote
+		req.Header.Set("X-Forwarded-For", "1.2.3.4")
+		if w := send(req); !strings.Contains(w.Body.String(), `"data":"`+want+`"`) {
+			t.Errorf("client ip from %s: got %s, want %s", remote, w.Body.String(), want)
+		}
+	}
+}
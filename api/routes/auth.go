@@ -10,25 +10,43 @@ import (
 type AuthRoutes struct {
 	handler        *middlewares.RequestHandler
 	authController *controllers.JWTAuthController
+	oidcController *controllers.OIDCAuthController
+	authMiddleware *middlewares.JWTAuthMiddleware
+	rateLimit      *middlewares.RateLimitMiddleware
+	security       *middlewares.SecurityMiddleware
 }
 
 // NewAuthRoutes creates new user controller
 func NewAuthRoutes(
 	handler *middlewares.RequestHandler,
 	authController *controllers.JWTAuthController,
+	oidcController *controllers.OIDCAuthController,
+	authMiddleware *middlewares.JWTAuthMiddleware,
+	rateLimit *middlewares.RateLimitMiddleware,
+	security *middlewares.SecurityMiddleware,
 ) *AuthRoutes {
 	return &AuthRoutes{
 		handler:        handler,
 		authController: authController,
+		oidcController: oidcController,
+		authMiddleware: authMiddleware,
+		rateLimit:      rateLimit,
+		security:       security,
 	}
 }
 
 // Setup user routes
 func (s *AuthRoutes) Setup() {
 	logging.Info("Setting up routes")
-	auth := s.handler.Gin.Group("/auth")
+	// limited by client ip against password guessing
+	auth := s.handler.Gin.Group("/auth", s.rateLimit.Handler("auth"), s.security.JSONOnly())
 	{
 		auth.POST("/login", s.authController.SignIn)
 		auth.POST("/register", s.authController.Register)
+		auth.POST("/refresh", s.authController.Refresh)
+		auth.POST("/logout", s.authMiddleware.Handler(), s.authController.Logout)
+		auth.GET("/oidc/:provider/login", s.oidcController.Login)
+		auth.GET("/oidc/:provider/callback", s.oidcController.Callback)
 	}
+	s.handler.Gin.GET("/.well-known/jwks.json", s.authController.JWKS)
 }
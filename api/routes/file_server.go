@@ -1,31 +1,44 @@
 package routes
 
 import (
+	"context"
+
 	"github.com/dean2032/go-project-layout/api/middlewares"
 	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/health"
 	"github.com/dean2032/go-project-layout/utils/logging"
 	"github.com/gin-gonic/gin"
 )
 
 // FileRoutes struct
 type FileRoutes struct {
-	handler *middlewares.RequestHandler
-	cfg     *config.Config
+	handler  *middlewares.RequestHandler
+	cfg      *config.Config
+	registry *health.Registry
 }
 
 // NewUserRoutes creates new user controller
 func NewFileRoutes(
 	handler *middlewares.RequestHandler,
 	cfg *config.Config,
+	registry *health.Registry,
 ) *FileRoutes {
 	return &FileRoutes{
-		handler: handler,
-		cfg:     cfg,
+		handler:  handler,
+		cfg:      cfg,
+		registry: registry,
 	}
 }
 
-// Setup user routes
+// Setup user routes, public dir is checked for readiness
 func (s *FileRoutes) Setup() {
 	logging.Infof("Setting up file routes on cfg.PublicDir: %s", s.cfg.PublicDir)
+	s.registry.Register("public_dir", func(ctx context.Context) error {
+		if problems := config.RequirePublicDir(s.cfg); len(problems) > 0 {
+			return errors.New(problems[0])
+		}
+		return nil
+	})
 	s.handler.Gin.StaticFS("/", gin.Dir(s.cfg.PublicDir, true))
 }
@@ -2,9 +2,12 @@ package routes
 
 import (
 	"net/http/pprof"
+	"sync/atomic"
 
+	"github.com/dean2032/go-project-layout/api/controllers"
 	"github.com/dean2032/go-project-layout/api/middlewares"
 	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/errors"
 	"github.com/dean2032/go-project-layout/utils/logging"
 	"github.com/gin-gonic/gin"
 )
@@ -13,25 +16,46 @@ import (
 type PprofRoutes struct {
 	handler *middlewares.RequestHandler
 	cfg     *config.Config
+	watcher *config.Watcher
+	enabled atomic.Bool
 }
 
 // NewUserRoutes creates new user controller
 func NewPprofRoutes(
 	handler *middlewares.RequestHandler,
 	cfg *config.Config,
+	watcher *config.Watcher,
 ) *PprofRoutes {
 	return &PprofRoutes{
 		handler: handler,
 		cfg:     cfg,
+		watcher: watcher,
 	}
 }
 
 // Setup user routes
+// Routes can be disabled and enabled again by reloading pprof_path
+// with empty and the original path, other paths require restart.
 func (s *PprofRoutes) Setup() {
-	if s.cfg.PprofPath != "" {
-		logging.Infof("Setting up pprof routes on %s", s.cfg.PprofPath)
-		registerPprof(s.handler.Gin.Group(s.cfg.PprofPath))
+	path := s.cfg.PprofPath
+	if path == "" {
+		return
 	}
+	logging.Infof("Setting up pprof routes on %s", path)
+	s.enabled.Store(true)
+	s.watcher.Subscribe(func(prev, cur *config.Config) {
+		if cur.PprofPath != "" && cur.PprofPath != path {
+			logging.Warnf("pprof path changed to %s, requires restart to apply", cur.PprofPath)
+		}
+		s.enabled.Store(cur.PprofPath == path)
+	})
+	group := s.handler.Gin.Group(path)
+	group.Use(func(c *gin.Context) {
+		if !s.enabled.Load() {
+			controllers.OnError(c, errors.CodeErrorf(errors.NotFound, "%s not found", c.Request.URL.Path))
+		}
+	})
+	registerPprof(group)
 }
 
 func registerPprof(router *gin.RouterGroup) {
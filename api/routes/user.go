@@ -3,14 +3,18 @@ package routes
 import (
 	"github.com/dean2032/go-project-layout/api/controllers"
 	"github.com/dean2032/go-project-layout/api/middlewares"
+	"github.com/dean2032/go-project-layout/constants"
 	"github.com/dean2032/go-project-layout/utils/logging"
 )
 
 // UserRoutes struct
 type UserRoutes struct {
-	handler        *middlewares.RequestHandler
-	userController *controllers.UserController
-	authMiddleware *middlewares.JWTAuthMiddleware
+	handler          *middlewares.RequestHandler
+	userController   *controllers.UserController
+	authMiddleware   *middlewares.JWTAuthMiddleware
+	apiKeyMiddleware *middlewares.APIKeyAuthMiddleware
+	rateLimit        *middlewares.RateLimitMiddleware
+	security         *middlewares.SecurityMiddleware
 }
 
 // NewUserRoutes creates new user controller
@@ -18,23 +22,38 @@ func NewUserRoutes(
 	handler *middlewares.RequestHandler,
 	userController *controllers.UserController,
 	authMiddleware *middlewares.JWTAuthMiddleware,
+	apiKeyMiddleware *middlewares.APIKeyAuthMiddleware,
+	rateLimit *middlewares.RateLimitMiddleware,
+	security *middlewares.SecurityMiddleware,
 ) *UserRoutes {
 	return &UserRoutes{
-		handler:        handler,
-		userController: userController,
-		authMiddleware: authMiddleware,
+		handler:          handler,
+		userController:   userController,
+		authMiddleware:   authMiddleware,
+		apiKeyMiddleware: apiKeyMiddleware,
+		rateLimit:        rateLimit,
+		security:         security,
 	}
 }
 
 // Setup user routes
 func (s *UserRoutes) Setup() {
 	logging.Info("Setting up routes")
-	api := s.handler.Gin.Group("/api").Use(s.authMiddleware.Handler())
+	// batch jobs call with api key, users with access token, each of them is rate limited
+	api := s.handler.Gin.Group("/api").Use(
+		s.apiKeyMiddleware.HandlerOr(s.authMiddleware.Handler()),
+		s.rateLimit.Handler("api"),
+		s.security.JSONOnly(),
+	)
 	{
-		api.GET("/user", s.userController.GetUser)
-		api.GET("/user/:id", s.userController.GetOneUser)
-		api.POST("/user", s.userController.SaveUser)
-		api.POST("/user/:id", s.userController.UpdateUser)
-		api.DELETE("/user/:id", s.userController.DeleteUser)
+		read := middlewares.RequireScopes(constants.ScopeUsersRead)
+		write := middlewares.RequireScopes(constants.ScopeUsersWrite)
+		api.GET("/user", read, s.userController.GetUser)
+		api.GET("/user/:id", read, s.userController.GetOneUser)
+		api.POST("/user", write, s.userController.SaveUser)
+		api.POST("/user/:id", write, s.userController.UpdateUser)
+		api.PUT("/user/:id", write, s.userController.UpdateUser)
+		api.PATCH("/user/:id", write, s.userController.UpdateUser)
+		api.DELETE("/user/:id", write, s.userController.DeleteUser)
 	}
 }
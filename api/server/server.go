@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/dean2032/go-project-layout/api/middlewares"
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/health"
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"github.com/dean2032/go-project-layout/utils/metrics"
+	"go.uber.org/fx"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// Module exports http server to container
+var Module = fx.Provide(NewHTTPServer)
+
+// HTTPServer http server bound to fx lifecycle
+type HTTPServer struct {
+	*http.Server
+	// redirect redirects plain http to https if tls.redirect_port is set
+	redirect *http.Server
+}
+
+// NewHTTPServer creates a http server serving the gin engine of handler, https if tls is configured,
+// it starts listening on app start and drains in-flight requests on app stop
+func NewHTTPServer(
+	lc fx.Lifecycle,
+	shutdowner fx.Shutdowner,
+	cfg *config.Config,
+	handler *middlewares.RequestHandler,
+	m *metrics.Metrics,
+	registry *health.Registry,
+) *HTTPServer {
+	s := &HTTPServer{
+		Server: &http.Server{
+			Addr:           ":" + cfg.ListenPort(),
+			Handler:        withHealth(withMetrics(withClientCert(handler.Gin), cfg.MetricsPath, m), registry),
+			MaxHeaderBytes: cfg.Security.MaxHeaderBytes,
+		},
+	}
+	if cfg.TLS.RedirectPort != "" {
+		_, port, _ := net.SplitHostPort(s.Addr)
+		s.redirect = &http.Server{
+			Addr:           ":" + cfg.TLS.RedirectPort,
+			Handler:        redirectHandler(port),
+			MaxHeaderBytes: cfg.Security.MaxHeaderBytes,
+		}
+	}
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			if cfg.TLS.Enabled() {
+				// certificates are loaded on start so that invalid ones fail app start
+				reloader, err := newCertReloader(cfg.TLS)
+				if err != nil {
+					return err
+				}
+				s.TLSConfig = reloader.TLSConfig()
+			}
+			if err := s.listen(s.Server, shutdowner); err != nil {
+				return err
+			}
+			if s.redirect != nil {
+				if err := s.listen(s.redirect, shutdowner); err != nil {
+					// OnStop isn't called for a failed start, the serving server is closed here
+					return multierr.Append(err, s.Close())
+				}
+			}
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logging.Infof("http server on %s shutting down", s.Addr)
+			// probes in flight and load balancers see the app not ready while draining
+			registry.ShuttingDown()
+			// load balancers keep routing requests until their probes fail, new requests are
+			// still served while they notice it
+			if delay := cfg.Health.ShutdownDelayDuration(); delay > 0 {
+				logging.Infof("http server not ready, stops accepting requests in %s", delay)
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+				}
+			}
+			var err error
+			if s.redirect != nil {
+				if e := s.redirect.Shutdown(ctx); e != nil {
+					err = errors.Wrap(e, "http redirect server shutdown")
+				}
+			}
+			// stop accepting new connections and wait for in-flight requests
+			if e := s.Shutdown(ctx); e != nil {
+				return multierr.Append(err, errors.Wrap(e, "http server shutdown"))
+			}
+			logging.Info("http server drained")
+			return err
+		},
+	})
+	return s
+}
+
+// listen listens on address of srv and serves in background, https if srv has tls config.
+// it listens synchronously so that errors like port in use fail app start
+func (s *HTTPServer) listen(srv *http.Server, shutdowner fx.Shutdowner) error {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return errors.Wrapf(err, "listen on %s fail", srv.Addr)
+	}
+	// actual address if port is 0
+	srv.Addr = ln.Addr().String()
+	scheme := "http"
+	if srv.TLSConfig != nil {
+		scheme = "https"
+		ln = tls.NewListener(ln, srv.TLSConfig)
+	}
+	logging.Infof("%s server listening on %s", scheme, srv.Addr)
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logging.Error("http server stopped unexpectedly", zap.Error(err))
+			_ = shutdowner.Shutdown()
+		}
+	}()
+	return nil
+}
+
+// withHealth serves liveness and readiness probes of registry besides routes of h, before routing like withMetrics
+func withHealth(h http.Handler, registry *health.Registry) http.Handler {
+	liveness, readiness := registry.LivenessHandler(), registry.ReadinessHandler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case health.LivenessPath:
+			liveness.ServeHTTP(w, r)
+		case health.ReadinessPath:
+			readiness.ServeHTTP(w, r)
+		default:
+			h.ServeHTTP(w, r)
+		}
+	})
+}
+
+// withMetrics serves metrics on path besides routes of h, path is served before routing
+// so that it doesn't conflict with catch-all routes like the file server's
+func withMetrics(h http.Handler, path string, m *metrics.Metrics) http.Handler {
+	if path == "" {
+		return h
+	}
+	metricsHandler := m.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == path {
+			metricsHandler.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
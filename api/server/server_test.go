@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dean2032/go-project-layout/api/middlewares"
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/health"
+	"github.com/dean2032/go-project-layout/utils/metrics"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/fx/fxtest"
+)
+
+func TestShutdownDelay(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ServerPort = "0"
+	cfg.Health.ShutdownDelay = 1
+
+	lc := fxtest.NewLifecycle(t)
+	m := metrics.New()
+	handler := middlewares.NewRequestHandler(cfg, config.NewWatcher(lc, cfg), m, sdktrace.NewTracerProvider())
+	s := NewHTTPServer(lc, nopShutdowner{}, cfg, handler, m, health.NewRegistry(cfg))
+	lc.RequireStart()
+
+	_, port, _ := net.SplitHostPort(s.Addr)
+	url := "http://127.0.0.1:" + port + health.ReadinessPath
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	get := func() (int, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+	if code, err := get(); err != nil || code != http.StatusOK {
+		t.Fatal("ready before shutdown expected", code, err)
+	}
+
+	stopped := make(chan error, 1)
+	started := time.Now()
+	go func() { stopped <- lc.Stop(context.Background()) }()
+	code, err := get()
+	for ; err == nil && code == http.StatusOK && time.Since(started) < time.Second; code, err = get() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil || code != http.StatusServiceUnavailable {
+		t.Error("not ready response expected while shutdown is delayed", code, err)
+	}
+
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed < time.Second {
+		t.Error("server should accept requests for the delay, stopped in", elapsed)
+	}
+	if _, err := get(); err == nil {
+		t.Error("server should be closed after shutdown")
+	}
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/services"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"go.uber.org/zap"
+)
+
+// certCheckInterval min interval to check certificate files for rotation
+var certCheckInterval = time.Second
+
+// certReloader serves certificate and client ca of files of cfg, files are checked on handshakes
+// and reloaded once their modification time changes, so rotated certificates apply without restart
+type certReloader struct {
+	cfg config.TLSConfig
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	modTimes  []time.Time
+	tlsConfig *tls.Config
+}
+
+// newCertReloader loads files of cfg, fails if they are invalid
+func newCertReloader(cfg config.TLSConfig) (*certReloader, error) {
+	r := &certReloader{cfg: cfg}
+	modTimes, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTimes); err != nil {
+		return nil, err
+	}
+	r.checkedAt = time.Now()
+	return r, nil
+}
+
+// TLSConfig returns tls config of server resolving config of each connection by the reloader
+func (r *certReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		NextProtos:         []string{"h2", "http/1.1"},
+		GetConfigForClient: r.getConfigForClient,
+	}
+}
+
+// getConfigForClient returns the loaded config, reloads files if they changed.
+// the previous config is kept if files are invalid e.g. while being rotated
+func (r *certReloader) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checkedAt) < certCheckInterval {
+		return r.tlsConfig, nil
+	}
+	r.checkedAt = time.Now()
+	modTimes, err := r.stat()
+	if err != nil {
+		logging.Warn("check tls certificate files fail", zap.Error(err))
+		return r.tlsConfig, nil
+	}
+	if equalTimes(modTimes, r.modTimes) {
+		return r.tlsConfig, nil
+	}
+	// files failed to load are not retried until they change again
+	r.modTimes = modTimes
+	if err := r.load(modTimes); err != nil {
+		logging.Error("reload tls certificates fail, previous ones are kept", zap.Error(err))
+		return r.tlsConfig, nil
+	}
+	logging.Infof("tls certificates reloaded from %s", r.cfg.CertFile)
+	return r.tlsConfig, nil
+}
+
+// files returns paths of files of the reloader
+func (r *certReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+// stat returns modification times of files
+func (r *certReloader) stat() ([]time.Time, error) {
+	var modTimes []time.Time
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "stat %s fail", file)
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	return modTimes, nil
+}
+
+// load loads files into tls config of connections
+func (r *certReloader) load(modTimes []time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return errors.Wrapf(err, "load certificate %s fail", r.cfg.CertFile)
+	}
+	c := r.TLSConfig()
+	c.GetConfigForClient = nil
+	c.Certificates = []tls.Certificate{cert}
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return errors.Wrapf(err, "read client ca %s fail", r.cfg.ClientCAFile)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.Errorf("no certificate found in client ca %s", r.cfg.ClientCAFile)
+		}
+		c.ClientCAs = pool
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+		if r.cfg.ClientAuth == config.ClientAuthOptional {
+			c.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+	r.modTimes = modTimes
+	r.tlsConfig = c
+	return nil
+}
+
+// equalTimes returns whether a and b are the same times
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// withClientCert sets identity of verified client certificate to context of requests of mutual tls
+func withClientCert(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cc, ok := services.ClientCertOf(r.TLS); ok {
+			r = r.WithContext(services.WithClientCert(r.Context(), cc))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// redirectHandler redirects requests to https on port, method and body are kept by 308
+func redirectHandler(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			// no port in host
+			host = strings.Trim(r.Host, "[]")
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dean2032/go-project-layout/api/middlewares"
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/services"
+	"github.com/dean2032/go-project-layout/utils/health"
+	"github.com/dean2032/go-project-layout/utils/metrics"
+	"github.com/gin-gonic/gin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert creates a certificate of name signed by parent, a self-signed ca if parent is nil
+func newTestCert(t *testing.T, serial int64, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type nopShutdowner struct{}
+
+func (nopShutdowner) Shutdown(...fx.ShutdownOption) error { return nil }
+
+func TestTLS(t *testing.T) {
+	defer func(interval time.Duration) { certCheckInterval = interval }(certCheckInterval)
+	certCheckInterval = 0
+
+	dir := t.TempDir()
+	ca := newTestCert(t, 1, "ca", nil)
+	server := newTestCert(t, 2, "server", ca)
+	client := newTestCert(t, 3, "client-a", ca)
+	cfg := config.DefaultConfig()
+	cfg.ServerPort = "0"
+	cfg.TLS.CertFile = filepath.Join(dir, "tls.crt")
+	cfg.TLS.KeyFile = filepath.Join(dir, "tls.key")
+	cfg.TLS.ClientCAFile = filepath.Join(dir, "ca.crt")
+	cfg.Health.ShutdownDelay = 0
+	now := time.Now()
+	writeFile(t, cfg.TLS.CertFile, server.certPEM, now)
+	writeFile(t, cfg.TLS.KeyFile, server.keyPEM, now)
+	writeFile(t, cfg.TLS.ClientCAFile, ca.certPEM, now)
+
+	lc := fxtest.NewLifecycle(t)
+	m := metrics.New()
+	handler := middlewares.NewRequestHandler(cfg, config.NewWatcher(lc, cfg), m, sdktrace.NewTracerProvider())
+	handler.Gin.GET("/whoami", func(c *gin.Context) {
+		cc, ok := services.ClientCertFromContext(c)
+		if !ok {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		c.String(http.StatusOK, cc.CommonName)
+	})
+	s := NewHTTPServer(lc, nopShutdowner{}, cfg, handler, m, health.NewRegistry(cfg))
+	lc.RequireStart()
+	defer lc.RequireStop()
+
+	_, port, _ := net.SplitHostPort(s.Addr)
+	url := "https://127.0.0.1:" + port + "/whoami"
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(certs ...tls.Certificate) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certs},
+			DisableKeepAlives: true,
+		}}
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+	clientCert := tls.Certificate{Certificate: [][]byte{client.cert.Raw}, PrivateKey: client.key}
+
+	resp, err := get(clientCert)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatal("request with client certificate fail", resp, err)
+	}
+	if _, err := get(); err == nil {
+		t.Error("client certificate should be required")
+	}
+
+	// rotate server certificate
+	rotated := newTestCert(t, 4, "server", ca)
+	later := now.Add(time.Minute)
+	writeFile(t, cfg.TLS.CertFile, rotated.certPEM, later)
+	writeFile(t, cfg.TLS.KeyFile, rotated.keyPEM, later)
+	resp, err = get(clientCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serial := resp.TLS.PeerCertificates[0].SerialNumber.Int64(); serial != 4 {
+		t.Error("rotated certificate should be served, got serial", serial)
+	}
+
+	// invalid files keep the previous certificate
+	writeFile(t, cfg.TLS.KeyFile, server.keyPEM, later.Add(time.Minute))
+	resp, err = get(clientCert)
+	if err != nil || resp.TLS.PeerCertificates[0].SerialNumber.Int64() != 4 {
+		t.Error("previous certificate should be kept", err)
+	}
+}
+
+func TestRedirectListenFail(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	_, busyPort, _ := net.SplitHostPort(busy.Addr().String())
+
+	dir := t.TempDir()
+	ca := newTestCert(t, 1, "ca", nil)
+	server := newTestCert(t, 2, "server", ca)
+	cfg := config.DefaultConfig()
+	cfg.ServerPort = "0"
+	cfg.TLS.CertFile = filepath.Join(dir, "tls.crt")
+	cfg.TLS.KeyFile = filepath.Join(dir, "tls.key")
+	cfg.TLS.RedirectPort = busyPort
+	writeFile(t, cfg.TLS.CertFile, server.certPEM, time.Now())
+	writeFile(t, cfg.TLS.KeyFile, server.keyPEM, time.Now())
+
+	lc := fxtest.NewLifecycle(t)
+	m := metrics.New()
+	handler := middlewares.NewRequestHandler(cfg, config.NewWatcher(lc, cfg), m, sdktrace.NewTracerProvider())
+	s := NewHTTPServer(lc, nopShutdowner{}, cfg, handler, m, health.NewRegistry(cfg))
+	if err := lc.Start(context.Background()); err == nil {
+		t.Fatal("start should fail if redirect port is in use")
+	}
+	_, port, _ := net.SplitHostPort(s.Addr)
+	if conn, err := net.Dial("tcp", "127.0.0.1:"+port); err == nil {
+		conn.Close()
+		t.Error("https server should be closed after failed start")
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	for _, tc := range []struct {
+		port, host, location string
+	}{
+		{"8443", "example.com:8080", "https://example.com:8443/users?page=2"},
+		{"443", "example.com:8080", "https://example.com/users?page=2"},
+		{"443", "example.com", "https://example.com/users?page=2"},
+		{"443", "[::1]:8080", "https://[::1]/users?page=2"},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/users?page=2", nil)
+		r.Host = tc.host
+		redirectHandler(tc.port).ServeHTTP(w, r)
+		if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != tc.location {
+			t.Error(tc.host, "unexpected redirect", w.Code, w.Header().Get("Location"))
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/repo"
+	"github.com/dean2032/go-project-layout/services"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/health"
+	"github.com/dean2032/go-project-layout/utils/metrics"
+	"github.com/dean2032/go-project-layout/utils/tracing"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+)
+
+// apiKeyModules dependencies of api key commands, only database is connected
+var apiKeyModules = fx.Options(
+	config.Module,
+	config.Requires(config.RequireDB),
+	metrics.Module,
+	tracing.Module,
+	health.Module,
+	fx.Provide(repo.NewDatabase),
+	fx.Provide(repo.NewAPIKeyRepository),
+	fx.Provide(services.NewAPIKeyService),
+)
+
+// NewAPIKeyCommand creates command to create, list and revoke api keys
+func NewAPIKeyCommand() *cobra.Command {
+	apiKeyCmd := &cobra.Command{
+		Use:   "apikey",
+		Short: "create, list and revoke api keys of service to service calls",
+	}
+	apiKeyCmd.AddCommand(
+		newAPIKeyCreateCommand(),
+		newAPIKeyListCommand(),
+		newAPIKeyRevokeCommand(),
+	)
+	return apiKeyCmd
+}
+
+// withAPIKeyService runs fn with api key service, database is closed after it
+func withAPIKeyService(fn func(s *services.APIKeyService) error) error {
+	var s *services.APIKeyService
+	app := fx.New(fx.NopLogger, apiKeyModules, fx.Populate(&s))
+	ctx, cancel := context.WithTimeout(context.Background(), app.StartTimeout())
+	defer cancel()
+	if err := app.Start(ctx); err != nil {
+		return err
+	}
+	err := fn(s)
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), app.StopTimeout())
+	defer stopCancel()
+	if stopErr := app.Stop(stopCtx); err == nil {
+		err = stopErr
+	}
+	return err
+}
+
+func newAPIKeyCreateCommand() *cobra.Command {
+	var (
+		owner  string
+		scopes []string
+		ttl    time.Duration
+	)
+	createCmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "create api key, the key is printed only once",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withAPIKeyService(func(s *services.APIKeyService) error {
+				key, apiKey, err := s.Create(args[0], owner, scopes, ttl)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "api key %d created, send it in header %s:\n%s\n",
+					apiKey.ID, services.APIKeyHeader, key)
+				return nil
+			})
+		},
+	}
+	createCmd.Flags().StringVar(&owner, "owner", "", "service or team responsible for the key")
+	createCmd.Flags().StringSliceVar(&scopes, "scopes", nil, "comma separated scopes granted, e.g. users:read,users:write")
+	createCmd.Flags().DurationVar(&ttl, "ttl", 0, "lifetime of the key, e.g. 2160h, never expires if 0")
+	_ = createCmd.MarkFlagRequired("owner")
+	return createCmd
+}
+
+func newAPIKeyListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "list api keys",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withAPIKeyService(func(s *services.APIKeyService) error {
+				keys, err := s.List()
+				if err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "ID\tNAME\tOWNER\tPREFIX\tSCOPES\tEXPIRES\tLAST USED\tREVOKED")
+				for _, k := range keys {
+					fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Owner, k.Prefix,
+						strings.ReplaceAll(k.Scopes, " ", ","), formatTime(k.ExpiresAt),
+						formatTime(k.LastUsedAt), formatTime(k.RevokedAt))
+				}
+				return w.Flush()
+			})
+		},
+	}
+}
+
+func newAPIKeyRevokeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke <id>",
+		Short: "revoke api key of id",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseUint(args[0], 10, 32)
+			if err != nil {
+				return errors.Errorf("invalid api key id %s", args[0])
+			}
+			return withAPIKeyService(func(s *services.APIKeyService) error {
+				if err := s.Revoke(uint(id)); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "api key %d revoked\n", id)
+				return nil
+			})
+		},
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"github.com/dean2032/go-project-layout/api/middlewares"
 	"github.com/dean2032/go-project-layout/api/routes"
+	"github.com/dean2032/go-project-layout/api/server"
 	"github.com/dean2032/go-project-layout/config"
 	"github.com/dean2032/go-project-layout/repo"
 	"github.com/dean2032/go-project-layout/utils"
@@ -17,25 +18,28 @@ func (s *ApiServerCommand) Short() string {
 	return "serve application"
 }
 
-func (s *ApiServerCommand) Setup(cmd *cobra.Command) {}
+func (s *ApiServerCommand) Setup(cmd *cobra.Command) {
+	config.RegisterFlags(cmd.Flags(), "server_port", "debug", "log_dir", "pprof_path")
+}
+
+// ConfigRequires returns config requires of command
+func (s *ApiServerCommand) ConfigRequires() []config.Require {
+	return []config.Require{config.RequireDB, config.RequireJWTSecret}
+}
 
 func (s *ApiServerCommand) Run() utils.CommandRunner {
 	return func(
 		middleware middlewares.Middlewares,
 		cfg *config.Config,
-		router *middlewares.RequestHandler,
 		route routes.ApiRoutes,
 		database *repo.Database,
+		// server starts serving on app start after routes are set up
+		_ *server.HTTPServer,
 	) {
 		middleware.Setup()
 		route.Setup()
 
 		logging.Info("Running api server")
-		if cfg.ServerPort == "" {
-			_ = router.Gin.Run()
-		} else {
-			_ = router.Gin.Run(":" + cfg.ServerPort)
-		}
 	}
 }
 
@@ -6,6 +6,7 @@ import (
 
 	"github.com/dean2032/go-project-layout/config"
 	"github.com/dean2032/go-project-layout/utils"
+	"github.com/dean2032/go-project-layout/utils/logging"
 	"github.com/spf13/cobra"
 	"go.uber.org/fx"
 )
@@ -16,6 +17,12 @@ var cmds = map[string]utils.Command{
 	"api_serverr": NewApiServerCommand(),
 }
 
+// configRequirer is implemented by commands which require some config fields,
+// the requires are checked before app starts
+type configRequirer interface {
+	ConfigRequires() []config.Require
+}
+
 // GetSubCommands gives a list of sub commands
 func GetSubCommands(opt fx.Option) []*cobra.Command {
 	var subCommands []*cobra.Command
@@ -38,13 +45,30 @@ func WrapSubCommand(name string, cmd utils.Command, opt fx.Option) *cobra.Comman
 				others = fx.Options()
 			}
 			opts := fx.Options(
-				others,
+				fx.StopTimeout(config.GetConfig().ShutdownDuration()),
 				fx.Invoke(cmd.Run()),
 			)
-			ctx := context.Background()
-			app := fx.New(opt, opts)
-			err := app.Start(ctx)
-			defer app.Stop(ctx)
+			if r, ok := cmd.(configRequirer); ok {
+				opts = fx.Options(opts, config.Requires(r.ConfigRequires()...))
+			}
+			// logger is initialized before invokes of opt, which apply log_level to it
+			app := fx.New(others, opt, opts)
+			startCtx, cancel := context.WithTimeout(context.Background(), app.StartTimeout())
+			defer cancel()
+			if err := app.Start(startCtx); err != nil {
+				log.Fatal(err)
+			}
+
+			// block until SIGINT/SIGTERM or fx.Shutdowner is triggered
+			sig := <-app.Done()
+			logging.Infof("received signal %s, shutting down", sig)
+
+			// hooks are stopped in reverse order: http server drains first,
+			// then database pools are closed
+			stopCtx, cancel := context.WithTimeout(context.Background(), app.StopTimeout())
+			defer cancel()
+			err := app.Stop(stopCtx)
+			logging.Sync()
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/dean2032/go-project-layout/services"
+	"go.uber.org/fx"
+)
+
+// TestCommandsDependencies checks dependencies of every command can be resolved without running them
+func TestCommandsDependencies(t *testing.T) {
+	for name, cmd := range cmds {
+		if err := fx.ValidateApp(CommonModules, fx.NopLogger, fx.Invoke(cmd.Run())); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+}
+
+func TestAPIKeyCommandDependencies(t *testing.T) {
+	var s *services.APIKeyService
+	if err := fx.ValidateApp(apiKeyModules, fx.NopLogger, fx.Populate(&s)); err != nil {
+		t.Error(err)
+	}
+}
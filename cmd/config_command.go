@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewConfigCommand creates command to show, validate and generate configuration
+func NewConfigCommand() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "show, validate and generate configuration",
+		// sub commands load config by themselves
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+	}
+	configCmd.AddCommand(
+		newConfigShowCommand(),
+		newConfigValidateCommand(),
+		newConfigInitCommand(),
+	)
+	return configCmd
+}
+
+func newConfigShowCommand() *cobra.Command {
+	format := "yaml"
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "print effective config merged from defaults, config file and env, secrets are redacted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(resolveConfigPath(), nil)
+			if err != nil {
+				return err
+			}
+			data, err := cfg.Redacted().Marshal(format, false)
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		},
+	}
+	showCmd.Flags().StringVarP(&format, "format", "f", format, "output format: json or yaml")
+	return showCmd
+}
+
+func newConfigValidateCommand() *cobra.Command {
+	command := ""
+	validateCmd := &cobra.Command{
+		Use:   "validate <file>",
+		Short: "validate config file, env APP_* is applied as when running",
+		Args:  cobra.ExactArgs(1),
+		// problems of config are reported, no need of usage
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var requires []config.Require
+			if command != "" {
+				sub, exists := cmds[command]
+				if !exists {
+					return errors.Errorf("unknown command %s, available: %s", command, commandNames())
+				}
+				if r, ok := sub.(configRequirer); ok {
+					requires = r.ConfigRequires()
+				}
+			}
+			if err := config.ValidateFile(args[0], requires...); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", args[0])
+			return nil
+		},
+	}
+	validateCmd.Flags().StringVar(&command, "for", "",
+		"also check fields required by the command, one of: "+commandNames())
+	return validateCmd
+}
+
+func newConfigInitCommand() *cobra.Command {
+	format := "yaml"
+	output := ""
+	force := false
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "generate default config file, yaml output has description of every field",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := config.GenerateDefault(format)
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				_, err = cmd.OutOrStdout().Write(data)
+				return err
+			}
+			if _, err := os.Stat(output); err == nil && !force {
+				return errors.Errorf("%s already exists, use --force to overwrite", output)
+			}
+			if err := os.WriteFile(output, data, 0644); err != nil {
+				return errors.WithStack(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "config written to %s\n", output)
+			return nil
+		},
+	}
+	initCmd.Flags().StringVarP(&format, "format", "f", format, "output format: json or yaml")
+	initCmd.Flags().StringVarP(&output, "output", "o", output, "output file, stdout if empty")
+	initCmd.Flags().BoolVar(&force, "force", force, "overwrite existing output file")
+	return initCmd
+}
+
+// resolveConfigPath returns path of --config, or env if not set
+func resolveConfigPath() string {
+	if configPath == "" {
+		return os.Getenv(configPathEnvKey)
+	}
+	return configPath
+}
+
+func commandNames() string {
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
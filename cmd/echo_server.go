@@ -1,8 +1,8 @@
 package cmd
 
 import (
-	"github.com/dean2032/go-project-layout/api/middlewares"
 	"github.com/dean2032/go-project-layout/api/routes"
+	"github.com/dean2032/go-project-layout/api/server"
 	"github.com/dean2032/go-project-layout/config"
 	"github.com/dean2032/go-project-layout/utils"
 	"github.com/dean2032/go-project-layout/utils/logging"
@@ -16,22 +16,19 @@ func (s *EchoServerCommand) Short() string {
 	return "simple echo server application"
 }
 
-func (s *EchoServerCommand) Setup(cmd *cobra.Command) {}
+func (s *EchoServerCommand) Setup(cmd *cobra.Command) {
+	config.RegisterFlags(cmd.Flags(), "server_port", "debug", "log_dir")
+}
 
 func (s *EchoServerCommand) Run() utils.CommandRunner {
 	return func(
 		cfg *config.Config,
-		router *middlewares.RequestHandler,
 		route *routes.EchoRoutes,
+		_ *server.HTTPServer,
 	) {
 		route.Setup()
 
 		logging.Infof("Running simple echo server on %s", cfg.ServerPort)
-		if cfg.ServerPort == "" {
-			_ = router.Gin.Run()
-		} else {
-			_ = router.Gin.Run(":" + cfg.ServerPort)
-		}
 	}
 }
 
@@ -1,8 +1,8 @@
 package cmd
 
 import (
-	"github.com/dean2032/go-project-layout/api/middlewares"
 	"github.com/dean2032/go-project-layout/api/routes"
+	"github.com/dean2032/go-project-layout/api/server"
 	"github.com/dean2032/go-project-layout/config"
 	"github.com/dean2032/go-project-layout/utils"
 	"github.com/dean2032/go-project-layout/utils/logging"
@@ -16,22 +16,24 @@ func (s *FileServerCommand) Short() string {
 	return "simple file server application"
 }
 
-func (s *FileServerCommand) Setup(cmd *cobra.Command) {}
+func (s *FileServerCommand) Setup(cmd *cobra.Command) {
+	config.RegisterFlags(cmd.Flags(), "server_port", "debug", "log_dir", "public_dir")
+}
+
+// ConfigRequires returns config requires of command
+func (s *FileServerCommand) ConfigRequires() []config.Require {
+	return []config.Require{config.RequirePublicDir}
+}
 
 func (s *FileServerCommand) Run() utils.CommandRunner {
 	return func(
 		cfg *config.Config,
-		router *middlewares.RequestHandler,
 		route *routes.FileRoutes,
+		_ *server.HTTPServer,
 	) {
 		route.Setup()
 
 		logging.Infof("Running simple file server on %s", cfg.ServerPort)
-		if cfg.ServerPort == "" {
-			_ = router.Gin.Run()
-		} else {
-			_ = router.Gin.Run(":" + cfg.ServerPort)
-		}
 	}
 }
 
@@ -4,9 +4,13 @@ import (
 	"github.com/dean2032/go-project-layout/api/controllers"
 	"github.com/dean2032/go-project-layout/api/middlewares"
 	"github.com/dean2032/go-project-layout/api/routes"
+	"github.com/dean2032/go-project-layout/api/server"
 	"github.com/dean2032/go-project-layout/config"
 	"github.com/dean2032/go-project-layout/repo"
 	"github.com/dean2032/go-project-layout/services"
+	"github.com/dean2032/go-project-layout/utils/health"
+	"github.com/dean2032/go-project-layout/utils/metrics"
+	"github.com/dean2032/go-project-layout/utils/tracing"
 	"go.uber.org/fx"
 )
 
@@ -15,6 +19,10 @@ var CommonModules = fx.Options(
 	routes.Module,
 	services.Module,
 	middlewares.Module,
+	server.Module,
 	repo.Module,
 	config.Module,
+	metrics.Module,
+	tracing.Module,
+	health.Module,
 )
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/dean2032/go-project-layout/config"
 	"github.com/spf13/cobra"
 )
 
@@ -13,10 +14,18 @@ var rootCmd = &cobra.Command{
 	Short:            "Clean architecture using gin framework",
 	Long:             `This is a command runner or web server for api architecture in golang. `,
 	TraverseChildren: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		_, err := config.Load(resolveConfigPath(), cmd.Flags())
+		return err
+	},
 }
 
+// configPathEnvKey environment variable of config file path, used when --config not set
+const configPathEnvKey = "APP_CONFIG"
+
 var (
-	verbose = false
+	verbose    = false
+	configPath = ""
 )
 
 func SetAppVersion(version, commit, buildTime string) {
@@ -25,6 +34,8 @@ func SetAppVersion(version, commit, buildTime string) {
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "",
+		"config file path (.json, .yaml, .yml or .toml), env "+configPathEnvKey+" is used if not set")
 }
 
 // App root of application
@@ -37,6 +48,8 @@ func NewApp() App {
 		Command: rootCmd,
 	}
 	app.AddCommand(GetSubCommands(CommonModules)...)
+	app.AddCommand(NewConfigCommand())
+	app.AddCommand(NewAPIKeyCommand())
 	return app
 }
 
@@ -1,10 +1,10 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/dean2032/go-project-layout/utils"
 	"github.com/dean2032/go-project-layout/utils/errors"
@@ -14,23 +14,289 @@ import (
 
 // Config ...
 type Config struct {
-	Debug      bool   `json:"debug"`
-	PprofPath  string `json:"pprof_path"`
+	Debug     bool   `json:"debug"`
+	PprofPath string `json:"pprof_path"`
+	// MetricsPath url path of prometheus metrics, empty disables the endpoint
+	MetricsPath string `json:"metrics_path"`
+	// ServerPort port of http server, DefaultServerPort if empty
 	ServerPort string `json:"server_port"`
 	PublicDir  string `json:"public_dir"`
 	LogDir     string `json:"log_dir"`
-	MainDB     string `json:"main_db"`
-	Redis      struct {
+	// LogLevel overrides level of logger: debug, info, warn, error, dpanic, panic, fatal
+	LogLevel string `json:"log_level"`
+	// SlowThreshold seconds of request handling to be logged as slow request
+	SlowThreshold int `json:"slow_threshold"`
+	// SentryDSN overrides env SENTRY_DSN
+	SentryDSN string     `json:"sentry_dsn"`
+	Cors      CorsConfig `json:"cors"`
+	MainDB    string     `json:"main_db"`
+	Redis     struct {
 		Address  string `json:"address"`
 		Password string `json:"password"`
 		DB       int    `json:"db"`
+		// PoolSize max connections of redis, 10 per cpu if 0
+		PoolSize int `json:"pool_size"`
 	} `json:"redis"`
-	JWTSecret            string `json:"jwt_secret"`
-	DBConnectionPoolSize int    `json:"db_connection_pool_size"`
+	Cache                CacheConfig   `json:"cache"`
+	JWTSecret            string        `json:"jwt_secret"`
+	JWT                  JWTConfig     `json:"jwt"`
+	OIDC                 OIDCConfig    `json:"oidc"`
+	Tracing              TracingConfig `json:"tracing"`
+	DBConnectionPoolSize int           `json:"db_connection_pool_size"`
+	// ShutdownTimeout max seconds to wait for in-flight requests on shutdown
+	ShutdownTimeout int             `json:"shutdown_timeout"`
+	Errors          ErrorsConfig    `json:"errors"`
+	Health          HealthConfig    `json:"health"`
+	RateLimit       RateLimitConfig `json:"rate_limit"`
+	Security        SecurityConfig  `json:"security"`
+	TLS             TLSConfig       `json:"tls"`
 }
 
-// Module ...
-var Module = fx.Provide(GetConfig)
+// CorsConfig cors policy of all routes, Groups overrides it for routes under url path prefixes
+type CorsConfig struct {
+	// AllowedOrigins exact origins or patterns with one wildcard e.g. https://*.example.com,
+	// "*" allows all origins, cross-origin requests are not allowed if empty
+	AllowedOrigins []string `json:"allowed_origins"`
+	AllowedMethods []string `json:"allowed_methods"`
+	// AllowedHeaders request headers allowed, "*" allows all
+	AllowedHeaders []string `json:"allowed_headers"`
+	// ExposedHeaders response headers readable by scripts besides the cors-safelisted ones
+	ExposedHeaders []string `json:"exposed_headers"`
+	// MaxAge seconds preflight responses are cached by browsers
+	MaxAge int `json:"max_age"`
+	// AllowCredentials allows cookies and authorization headers of browsers, not allowed with origin "*"
+	AllowCredentials bool `json:"allow_credentials"`
+	// Groups overrides by url path prefix of route group e.g. /api, fields not set are inherited
+	Groups map[string]CorsOverride `json:"groups"`
+}
+
+// CorsOverride overrides of cors policy for a route group, fields not set (null or 0) inherit the policy
+type CorsOverride struct {
+	AllowedOrigins   []string `json:"allowed_origins" yaml:"allowed_origins"`
+	AllowedMethods   []string `json:"allowed_methods" yaml:"allowed_methods"`
+	AllowedHeaders   []string `json:"allowed_headers" yaml:"allowed_headers"`
+	ExposedHeaders   []string `json:"exposed_headers" yaml:"exposed_headers"`
+	MaxAge           int      `json:"max_age" yaml:"max_age"`
+	AllowCredentials *bool    `json:"allow_credentials" yaml:"allow_credentials"`
+}
+
+// Group returns policy of route group of prefix, the policy itself if the group has no override
+func (c CorsConfig) Group(prefix string) CorsConfig {
+	policy := c
+	policy.Groups = nil
+	o, ok := c.Groups[prefix]
+	if !ok {
+		return policy
+	}
+	if o.AllowedOrigins != nil {
+		policy.AllowedOrigins = o.AllowedOrigins
+	}
+	if o.AllowedMethods != nil {
+		policy.AllowedMethods = o.AllowedMethods
+	}
+	if o.AllowedHeaders != nil {
+		policy.AllowedHeaders = o.AllowedHeaders
+	}
+	if o.ExposedHeaders != nil {
+		policy.ExposedHeaders = o.ExposedHeaders
+	}
+	if o.MaxAge != 0 {
+		policy.MaxAge = o.MaxAge
+	}
+	if o.AllowCredentials != nil {
+		policy.AllowCredentials = *o.AllowCredentials
+	}
+	return policy
+}
+
+// HealthConfig checks of readiness
+type HealthConfig struct {
+	// Timeout seconds of each check
+	Timeout int `json:"timeout"`
+	// CacheTTL seconds to reuse results of checks, 0 checks on every probe
+	CacheTTL int `json:"cache_ttl"`
+	// ShutdownDelay seconds /readyz fails before the server stops accepting requests on shutdown,
+	// for load balancers to stop routing to it. it's part of shutdown_timeout
+	ShutdownDelay int `json:"shutdown_delay"`
+}
+
+// cache backends
+const (
+	CacheBackendNone   = "none"
+	CacheBackendMemory = "memory"
+	CacheBackendRedis  = "redis"
+)
+
+// CacheConfig cache of services
+type CacheConfig struct {
+	// Backend one of none, memory and redis
+	Backend string `json:"backend"`
+	// TTL seconds of cached values
+	TTL int `json:"ttl"`
+	// Size max entries of memory cache
+	Size int `json:"size"`
+}
+
+// JWTConfig claims and lifetime of jwt tokens
+type JWTConfig struct {
+	// Issuer iss claim of issued tokens, checked by authorization if not empty
+	Issuer string `json:"issuer"`
+	// Audience aud claim of issued tokens, checked by authorization if not empty
+	Audience string `json:"audience"`
+	// AccessTTL seconds of access token lifetime
+	AccessTTL int `json:"access_ttl"`
+	// RefreshTTL seconds of refresh token lifetime
+	RefreshTTL int `json:"refresh_ttl"`
+	// RevocationStore store of revoked tokens: memory or redis
+	RevocationStore string `json:"revocation_store"`
+	// KeysDir directory of <kid>.pem keys to sign tokens asymmetrically, jwt_secret is used if empty
+	KeysDir string `json:"keys_dir"`
+	// SigningKID kid of private key to sign tokens, the last private key by kid if empty
+	SigningKID string `json:"signing_kid"`
+}
+
+// ErrorsConfig rendering of error responses
+type ErrorsConfig struct {
+	// StatusCodes overrides http status of error codes, e.g. {"6": 422}
+	StatusCodes map[string]int `json:"status_codes"`
+	// ProblemJSON renders errors as RFC 7807 application/problem+json instead of Response
+	ProblemJSON bool `json:"problem_json"`
+}
+
+// OIDCConfig sign in by OpenID Connect providers
+type OIDCConfig struct {
+	// RedirectBaseURL external url of this server, callback url of provider is
+	// <redirect_base_url>/auth/oidc/<name>/callback
+	RedirectBaseURL string `json:"redirect_base_url"`
+	// Providers providers by name
+	Providers map[string]OIDCProvider `json:"providers"`
+}
+
+// OIDCProvider client registration at OpenID Connect provider
+type OIDCProvider struct {
+	// Issuer issuer url, discovery document is at <issuer>/.well-known/openid-configuration
+	Issuer       string `json:"issuer" yaml:"issuer"`
+	ClientID     string `json:"client_id" yaml:"client_id"`
+	ClientSecret string `json:"client_secret" yaml:"client_secret"`
+	// Scopes requested besides openid, email is required to link users
+	Scopes []string `json:"scopes" yaml:"scopes"`
+}
+
+// SecurityConfig hardening of requests and responses
+type SecurityConfig struct {
+	// Enabled sets security headers, limits request body and rejects non-json bodies of json routes
+	Enabled bool `json:"enabled"`
+	// HSTSMaxAge seconds of Strict-Transport-Security, 0 omits the header
+	HSTSMaxAge int `json:"hsts_max_age"`
+	// ContentSecurityPolicy Content-Security-Policy of responses, empty omits the header
+	ContentSecurityPolicy string `json:"content_security_policy"`
+	// FrameOptions X-Frame-Options of responses: DENY or SAMEORIGIN, empty omits the header
+	FrameOptions string `json:"frame_options"`
+	// ReferrerPolicy Referrer-Policy of responses, empty omits the header
+	ReferrerPolicy string `json:"referrer_policy"`
+	// MaxBodyBytes max bytes of request body, 0 is unlimited
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+	// MaxHeaderBytes max bytes of request headers, applied even if not Enabled, 1MB if 0
+	MaxHeaderBytes int `json:"max_header_bytes"`
+	// TrustedProxies ips or cidrs of proxies whose X-Forwarded-For and X-Real-IP are trusted for client ip,
+	// applied even if not Enabled, client ip is the peer address if empty
+	TrustedProxies []string `json:"trusted_proxies"`
+}
+
+// client certificate verification of mutual tls
+const (
+	ClientAuthRequire  = "require"
+	ClientAuthOptional = "optional"
+)
+
+// TLSConfig https of the server, certificate files are reloaded once rotated
+type TLSConfig struct {
+	// CertFile pem certificate chain of the server, plain http is served if empty
+	CertFile string `json:"cert_file"`
+	// KeyFile pem private key of CertFile
+	KeyFile string `json:"key_file"`
+	// ClientCAFile pem ca bundle to verify client certificates by, mutual tls is disabled if empty
+	ClientCAFile string `json:"client_ca_file"`
+	// ClientAuth require or optional client certificate if ClientCAFile is set,
+	// require applies to health probes as well
+	ClientAuth string `json:"client_auth"`
+	// RedirectPort port of plain http listener redirecting to https, disabled if empty
+	RedirectPort string `json:"redirect_port"`
+}
+
+// Enabled returns whether https is served
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// rate limit backends
+const (
+	RateLimitBackendNone   = "none"
+	RateLimitBackendMemory = "memory"
+	RateLimitBackendRedis  = "redis"
+)
+
+// RateLimitGlobal group of rate_limit.limits limiting all requests by client ip
+const RateLimitGlobal = "global"
+
+// RateLimitConfig limits of requests by client, clients are authenticated users and api keys, or ip of others
+type RateLimitConfig struct {
+	// Backend one of none, memory and redis, none disables rate limiting and is the default.
+	// anonymous clients are limited by client ip, set security.trusted_proxies behind load
+	// balancers or all of their clients share the limits of the proxy ip
+	Backend string `json:"backend"`
+	// Limits by route group, e.g. auth and api. global limits all requests by client ip
+	// before database transactions begin and before authentication
+	Limits map[string]RateLimit `json:"limits"`
+}
+
+// RateLimit requests allowed per period, all of them may be sent in a burst
+type RateLimit struct {
+	// Requests 0 disables the limit
+	Requests int `json:"requests" yaml:"requests"`
+	// Period seconds
+	Period int `json:"period" yaml:"period"`
+}
+
+// PeriodDuration returns Period as time.Duration
+func (l RateLimit) PeriodDuration() time.Duration {
+	return time.Duration(l.Period) * time.Second
+}
+
+// tracing exporters
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
+// TracingConfig OpenTelemetry tracing
+type TracingConfig struct {
+	// Exporter one of none, stdout and otlp, spans are still created for trace ids of logs if none
+	Exporter string `json:"exporter"`
+	// Endpoint host:port of otlp http receiver, env OTEL_EXPORTER_OTLP_ENDPOINT is used if empty
+	Endpoint string `json:"endpoint"`
+	// Insecure sends spans to otlp receiver without tls
+	Insecure bool `json:"insecure"`
+	// ServiceName service.name of resource
+	ServiceName string `json:"service_name"`
+	// SampleRatio ratio of root spans sampled, child spans follow their parent
+	SampleRatio float64 `json:"sample_ratio"`
+}
+
+// revocation stores
+const (
+	RevocationStoreMemory = "memory"
+	RevocationStoreRedis  = "redis"
+)
+
+// Module provides global config and validates it with Require of Requires
+var Module = fx.Options(
+	fx.Provide(GetConfig),
+	fx.Provide(NewWatcher),
+	fx.Invoke(validate),
+	fx.Invoke(setupLogging),
+)
 
 // cfg global config
 var globalCfg = DefaultConfig()
@@ -40,7 +306,8 @@ func GetConfig() *Config {
 	return globalCfg
 }
 
-// LoadFromPath ...
+// LoadFromPath loads config file over DefaultConfig, return DefaultConfig if file not exist.
+// Use Load to apply environment variables and flags as well.
 func LoadFromPath(configPath string) (*Config, error) {
 	cfg := DefaultConfig()
 	if !utils.IsFileExist(configPath) {
@@ -50,12 +317,11 @@ func LoadFromPath(configPath string) (*Config, error) {
 	if err != nil {
 		return cfg, errors.Wrap(err, fmt.Sprintf("read file %s fail: ", configPath))
 	}
-	err = json.Unmarshal(data, &cfg)
+	err = decodeFile(cfg, configPath, data)
 	if err != nil {
-		return cfg, errors.Wrap(err, fmt.Sprintf("load yaml file %s fail: ", configPath))
+		return cfg, errors.Wrap(err, fmt.Sprintf("load config file %s fail: ", configPath))
 	}
 	fixConfigDirs(cfg)
-	globalCfg = cfg
 	return cfg, nil
 }
 
@@ -80,18 +346,124 @@ func fixConfigDirs(cfg *Config) {
 
 // DefaultConfig set default config
 func DefaultConfig() *Config {
-	cfg := &Config{
+	cfg := defaultConfig()
+	fixConfigDirs(cfg)
+	return cfg
+}
+
+// defaultConfig returns default config with relative dirs
+func defaultConfig() *Config {
+	return &Config{
 		Debug:                false,
 		ServerPort:           "8888",
 		LogDir:               "./log",
 		PublicDir:            ".",
 		PprofPath:            "/debug/pprof",
+		MetricsPath:          "/metrics",
 		DBConnectionPoolSize: 1000,
+		ShutdownTimeout:      30,
+		Health:               HealthConfig{Timeout: 2, CacheTTL: 1, ShutdownDelay: 5},
+		Security: SecurityConfig{
+			HSTSMaxAge:            180 * 24 * 3600,
+			ContentSecurityPolicy: "default-src 'self'; frame-ancestors 'none'",
+			FrameOptions:          "DENY",
+			ReferrerPolicy:        "strict-origin-when-cross-origin",
+			MaxBodyBytes:          1 << 20,
+			TrustedProxies:        []string{},
+		},
+		TLS: TLSConfig{ClientAuth: ClientAuthRequire},
+		RateLimit: RateLimitConfig{
+			Backend: RateLimitBackendNone,
+			Limits: map[string]RateLimit{
+				RateLimitGlobal: {Requests: 1200, Period: 60},
+				"auth":          {Requests: 20, Period: 60},
+				"api":           {Requests: 600, Period: 60},
+			},
+		},
+		SlowThreshold: 10,
+		Cors: CorsConfig{
+			AllowedOrigins: []string{},
+			AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "X-Trace-Id", "traceparent"},
+			ExposedHeaders: []string{"ETag", "X-Trace-Id", "Retry-After",
+				"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"},
+			MaxAge: 600,
+			Groups: map[string]CorsOverride{},
+		},
+		Errors: ErrorsConfig{StatusCodes: map[string]int{}},
+		Cache: CacheConfig{
+			Backend: CacheBackendMemory,
+			TTL:     60,
+			Size:    10000,
+		},
+		JWT: JWTConfig{
+			AccessTTL:       15 * 60,
+			RefreshTTL:      30 * 24 * 3600,
+			RevocationStore: RevocationStoreMemory,
+		},
+		OIDC: OIDCConfig{Providers: map[string]OIDCProvider{}},
+		Tracing: TracingConfig{
+			Exporter:    TracingExporterNone,
+			ServiceName: "go-project-layout",
+			SampleRatio: 1,
+		},
 		// db DSN example
 		MainDB: "user:password@tcp(host:port)/dbname?charset=utf8mb4&parseTime=True&loc=Local",
 	}
-	fixConfigDirs(cfg)
-	return cfg
+}
+
+// SlowDuration returns SlowThreshold as time.Duration
+func (c *Config) SlowDuration() time.Duration {
+	return time.Duration(c.SlowThreshold) * time.Second
+}
+
+// TTLDuration returns TTL as time.Duration
+func (c CacheConfig) TTLDuration() time.Duration {
+	return time.Duration(c.TTL) * time.Second
+}
+
+// AccessTTLDuration returns AccessTTL as time.Duration
+func (c JWTConfig) AccessTTLDuration() time.Duration {
+	return time.Duration(c.AccessTTL) * time.Second
+}
+
+// RefreshTTLDuration returns RefreshTTL as time.Duration
+func (c JWTConfig) RefreshTTLDuration() time.Duration {
+	return time.Duration(c.RefreshTTL) * time.Second
+}
+
+// TimeoutDuration returns Timeout as time.Duration
+func (c HealthConfig) TimeoutDuration() time.Duration {
+	return time.Duration(c.Timeout) * time.Second
+}
+
+// CacheTTLDuration returns CacheTTL as time.Duration
+func (c HealthConfig) CacheTTLDuration() time.Duration {
+	return time.Duration(c.CacheTTL) * time.Second
+}
+
+// ShutdownDelayDuration returns ShutdownDelay as time.Duration
+func (c HealthConfig) ShutdownDelayDuration() time.Duration {
+	return time.Duration(c.ShutdownDelay) * time.Second
+}
+
+// DefaultServerPort port of http server if server_port is empty
+const DefaultServerPort = "8080"
+
+// ListenPort returns ServerPort, DefaultServerPort if it's empty
+func (c *Config) ListenPort() string {
+	if c.ServerPort == "" {
+		return DefaultServerPort
+	}
+	return c.ServerPort
+}
+
+// ShutdownDuration returns ShutdownTimeout as time.Duration
+func (c *Config) ShutdownDuration() time.Duration {
+	if c.ShutdownTimeout <= 0 {
+		return fx.DefaultTimeout
+	}
+	return time.Duration(c.ShutdownTimeout) * time.Second
 }
 
 // GenLoggerModule ...
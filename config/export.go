@@ -0,0 +1,265 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/go-sql-driver/mysql"
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue replaces secrets in output
+const redactedValue = "******"
+
+// fieldDocs descriptions of config keys, written as comments of generated yaml config
+var fieldDocs = map[string]string{
+	"debug":                   "debug mode: console log, gin debug mode and sql logging",
+	"pprof_path":              "url path of pprof routes, empty disables pprof",
+	"metrics_path":            "url path of prometheus metrics served by every http server, empty disables it",
+	"server_port":             "port of http server, 8080 if empty",
+	"public_dir":              "root directory served by file_server",
+	"log_dir":                 "directory of access.log",
+	"log_level":               "logger level: debug, info, warn, error, dpanic, panic, fatal. empty uses the level by debug",
+	"slow_threshold":          "seconds of request handling to be logged as slow request",
+	"sentry_dsn":              "sentry dsn to capture error logs, overrides env SENTRY_DSN",
+	"cors":                    "cors policy",
+	"cors.allowed_origins":    "allowed origins, exact or with one wildcard e.g. https://*.example.com. \"*\" allows all, none if empty",
+	"cors.allowed_methods":    "allowed methods of cross-origin requests",
+	"cors.allowed_headers":    "allowed request headers of cross-origin requests, \"*\" allows all",
+	"cors.exposed_headers":    "response headers readable by scripts of other origins",
+	"cors.max_age":            "seconds preflight responses are cached by browsers",
+	"cors.allow_credentials":  "allow cookies and authorization headers of browsers, not allowed with origin \"*\"",
+	"cors.groups":             "overrides by url path prefix of route group: {\"/api\": {\"allowed_origins\": [...]}}, fields not set are inherited",
+	"main_db":                 "mysql dsn of primary database, dsn of read replicas follow line by line",
+	"redis":                   "redis connection",
+	"redis.address":           "host:port of redis",
+	"redis.password":          "password of redis",
+	"redis.db":                "database number of redis",
+	"redis.pool_size":         "max connections of redis, 10 connections per cpu if 0",
+	"cache":                   "cache of services",
+	"cache.backend":           "cache backend: none, memory or redis. redis requires redis.address",
+	"cache.ttl":               "seconds of cached values, 0 never expires",
+	"cache.size":              "max entries of memory cache",
+	"jwt_secret":              "secret to sign jwt tokens",
+	"jwt":                     "claims and lifetime of jwt tokens",
+	"jwt.issuer":              "iss claim of issued tokens, checked by authorization if not empty",
+	"jwt.audience":            "aud claim of issued tokens, checked by authorization if not empty",
+	"jwt.access_ttl":          "seconds of access token lifetime",
+	"jwt.refresh_ttl":         "seconds of refresh token lifetime, refresh tokens are rotated on every use",
+	"jwt.revocation_store":    "store of tokens revoked by logout: memory or redis. memory is not shared by instances",
+	"jwt.keys_dir":            "directory of <kid>.pem keys (RSA, EC or Ed25519) to sign tokens instead of jwt_secret, public keys only verify tokens",
+	"jwt.signing_kid":         "kid of private key to sign tokens, the last private key by kid if empty",
+	"oidc":                    "sign in by OpenID Connect providers, authorization code flow with PKCE",
+	"oidc.redirect_base_url":  "external url of this server, callback url registered at provider is <redirect_base_url>/auth/oidc/<name>/callback",
+	"oidc.providers":          "providers by name, e.g. {\"google\": {\"issuer\": \"https://accounts.google.com\", \"client_id\": \"...\", \"client_secret\": \"...\", \"scopes\": [\"email\", \"profile\"]}}",
+	"tracing":                 "OpenTelemetry tracing, W3C traceparent of requests is followed",
+	"tracing.exporter":        "exporter of spans: none, stdout or otlp. trace ids are logged even if none",
+	"tracing.endpoint":        "host:port of otlp http receiver, env OTEL_EXPORTER_OTLP_ENDPOINT is used if empty",
+	"tracing.insecure":        "send spans to otlp receiver without tls",
+	"tracing.service_name":    "service.name of traces",
+	"tracing.sample_ratio":    "ratio of traces started by this service to be sampled, 0 to 1",
+	"db_connection_pool_size": "max open connections of each database",
+	"shutdown_timeout":        "max seconds to wait for in-flight requests on shutdown",
+	"health":                  "dependency checks of /readyz",
+	"rate_limit":              "limits of requests by user, api key or client ip of anonymous requests",
+	"rate_limit.backend":      "rate limit backend: none, memory or redis, none disables limits. redis shares limits by instances and requires redis.address. set security.trusted_proxies behind load balancers, otherwise clients share the limits of the proxy ip",
+	"rate_limit.limits":       "limits by route group (global, auth, api): {\"api\": {\"requests\": 600, \"period\": 60}}, requests of period seconds, 0 disables the limit. global limits all requests by client ip before database and authentication, auth and api are limited after the transaction begins and api after the api key or token is verified",
+	"health.timeout":          "seconds of each check",
+	"health.cache_ttl":        "seconds to reuse results of checks, 0 checks on every probe",
+	"health.shutdown_delay":   "seconds /readyz fails before the server stops accepting requests on shutdown, part of shutdown_timeout",
+	"errors":                  "rendering of error responses",
+	"errors.status_codes":     "http status of error codes overriding the defaults, e.g. {\"6\": 422}",
+	"errors.problem_json":     "render errors as RFC 7807 application/problem+json, clients may request it by Accept header as well",
+
+	"security":                         "hardening of requests and responses",
+	"security.enabled":                 "set security headers, limit request body and reject non-json bodies of json routes",
+	"security.hsts_max_age":            "seconds of Strict-Transport-Security, 0 omits the header",
+	"security.content_security_policy": "Content-Security-Policy of responses, empty omits the header",
+	"security.frame_options":           "X-Frame-Options of responses: DENY or SAMEORIGIN, empty omits the header",
+	"security.referrer_policy":         "Referrer-Policy of responses, empty omits the header",
+	"security.max_body_bytes":          "max bytes of request body, 0 is unlimited",
+	"security.max_header_bytes":        "max bytes of request headers, 1MB if 0. applied even if not enabled",
+	"security.trusted_proxies":         "ips or cidrs of proxies trusted for X-Forwarded-For, client ip is the peer address if empty. applied even if not enabled",
+
+	"tls":                "https of the server, certificate files are reloaded once rotated without restart",
+	"tls.cert_file":      "pem certificate chain of the server, plain http is served if empty",
+	"tls.key_file":       "pem private key of cert_file",
+	"tls.client_ca_file": "pem ca bundle to verify client certificates by (mutual tls), disabled if empty",
+	"tls.client_auth":    "client certificate if client_ca_file is set: require or optional. require applies to /healthz and /readyz probes as well",
+	"tls.redirect_port":  "port of plain http listener redirecting to https, disabled if empty",
+}
+
+// secretKeys config keys holding secrets, redacted by Redacted
+var secretKeys = map[string]bool{
+	"main_db":        true,
+	"redis.password": true,
+	"jwt_secret":     true,
+	"sentry_dsn":     true,
+}
+
+// Redacted returns a copy of config with secrets and client secrets of oidc providers replaced,
+// passwords in dsn of main_db are replaced and the rest is kept for troubleshooting
+func (c *Config) Redacted() *Config {
+	cp := *c
+	if len(c.OIDC.Providers) > 0 {
+		cp.OIDC.Providers = make(map[string]OIDCProvider, len(c.OIDC.Providers))
+		for name, p := range c.OIDC.Providers {
+			if p.ClientSecret != "" {
+				p.ClientSecret = redactedValue
+			}
+			cp.OIDC.Providers[name] = p
+		}
+	}
+	for _, f := range walkFields(reflect.ValueOf(&cp).Elem(), "") {
+		if !secretKeys[f.key] || f.value.Kind() != reflect.String || f.value.String() == "" {
+			continue
+		}
+		if f.key == "main_db" {
+			f.value.SetString(redactDSN(f.value.String()))
+			continue
+		}
+		f.value.SetString(redactedValue)
+	}
+	return &cp
+}
+
+func redactDSN(dsns string) string {
+	lines := strings.Split(dsns, "\n")
+	for i, dsn := range lines {
+		if dsn == "" {
+			continue
+		}
+		parsed, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			lines[i] = redactedValue
+			continue
+		}
+		if parsed.Passwd != "" {
+			parsed.Passwd = redactedValue
+		}
+		lines[i] = parsed.FormatDSN()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Marshal encodes config in format json or yaml,
+// yaml output keeps field order of Config and has field descriptions as comments if withDocs
+func (c *Config) Marshal(format string, withDocs bool) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "json":
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(c); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case "yaml", "yml":
+		node, err := yamlNode(reflect.ValueOf(c).Elem(), "", withDocs)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(node); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, errors.Errorf("unsupported config format %s", format)
+	}
+}
+
+// yamlNode builds mapping node of struct v, keys are json tags
+func yamlNode(v reflect.Value, prefix string, withDocs bool) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || !sf.IsExported() {
+			continue
+		}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: name}
+		if withDocs {
+			key.HeadComment = fieldDocs[prefix+name]
+		}
+		var value *yaml.Node
+		if fv := v.Field(i); fv.Kind() == reflect.Struct {
+			var err error
+			if value, err = yamlNode(fv, prefix+name+".", withDocs); err != nil {
+				return nil, err
+			}
+		} else {
+			// values like maps of structs are encoded by yaml, structs of them need yaml tags
+			// for their keys to be the json ones, e.g. CorsOverride
+			value = &yaml.Node{}
+			if err := value.Encode(fv.Interface()); err != nil {
+				return nil, err
+			}
+			if fv.Kind() == reflect.Slice && fv.Len() == 0 {
+				// encode empty and nil slices as []
+				value = &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+			}
+		}
+		node.Content = append(node.Content, key, value)
+	}
+	return node, nil
+}
+
+// GenerateDefault encodes default config in format json or yaml, yaml output has field descriptions
+func GenerateDefault(format string) ([]byte, error) {
+	return defaultConfig().Marshal(format, true)
+}
+
+// ValidateFile validates config file strictly: unknown keys are reported as problems.
+// Environment variables are applied like Load, so secrets can be kept out of the file.
+func ValidateFile(configPath string, requires ...Require) error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return errors.Wrapf(err, "read file %s fail", configPath)
+	}
+	var problems []string
+	if err := checkUnknownKeys(configPath, data); err != nil {
+		problems = append(problems, err.Error())
+	}
+	cfg, err := load(configPath, nil)
+	if err != nil {
+		return &ValidationError{Problems: append(problems, err.Error())}
+	}
+	err = cfg.Validate(requires...)
+	if verr, ok := err.(*ValidationError); ok {
+		problems = append(problems, verr.Problems...)
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// checkUnknownKeys decodes data and fails on the first key not defined by Config
+func checkUnknownKeys(configPath string, data []byte) error {
+	raw, err := decodeRaw(configPath, data)
+	if err != nil {
+		return err
+	}
+	if raw != nil {
+		if data, err = json.Marshal(raw); err != nil {
+			return err
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&Config{}); err != nil && err != io.EOF {
+		// data of yaml and toml are converted to json, hide it from users
+		return errors.New(strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
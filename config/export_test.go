@@ -0,0 +1,49 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFieldDocsComplete(t *testing.T) {
+	for _, f := range walkFields(reflect.ValueOf(DefaultConfig()).Elem(), "") {
+		if fieldDocs[f.key] == "" {
+			t.Errorf("description of %s is missing in fieldDocs", f.key)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.JWTSecret = "secret"
+	cfg.MainDB = "user:pass@tcp(127.0.0.1:3306)/db\nuser:pass@tcp(127.0.0.2:3306)/db"
+	cfg.OIDC.Providers = map[string]OIDCProvider{"google": {Issuer: "https://accounts.google.com", ClientSecret: "secret"}}
+
+	redacted := cfg.Redacted()
+	if redacted.JWTSecret != redactedValue {
+		t.Error(redacted.JWTSecret)
+	}
+	if strings.Contains(redacted.MainDB, "pass") || !strings.Contains(redacted.MainDB, "127.0.0.2") {
+		t.Error(redacted.MainDB)
+	}
+	if p := redacted.OIDC.Providers["google"]; p.ClientSecret != redactedValue || p.Issuer == "" {
+		t.Error(p)
+	}
+	if cfg.JWTSecret != "secret" || cfg.OIDC.Providers["google"].ClientSecret != "secret" {
+		t.Error("original config should not be changed")
+	}
+}
+
+func TestGenerateDefaultLoadable(t *testing.T) {
+	for _, format := range []string{"json", "yaml"} {
+		data, err := GenerateDefault(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := writeFile(t, "config."+format, string(data))
+		if err := ValidateFile(p); err != nil {
+			t.Error(format, err)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/dean2032/go-project-layout/utils"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvPrefix is prefix of environment variables overriding config fields
+	EnvPrefix = "APP_"
+
+	// flagKeyAnnotation annotates a flag with the config key it overrides
+	flagKeyAnnotation = "config_key"
+)
+
+// Load loads config in order of precedence, later ones override former ones:
+//  1. DefaultConfig
+//  2. config file of configPath, format is detected by extension (.json, .yaml, .yml, .toml)
+//  3. environment variables: EnvPrefix + upper cased json key path joined with "_",
+//     e.g. APP_SERVER_PORT, APP_JWT_SECRET, APP_REDIS_ADDRESS
+//  4. command line flags registered by RegisterFlags and set explicitly
+//
+// Secrets like jwt_secret and main_db can be passed by environment variables
+// so they never need to be written to the config file.
+// The loaded config replaces the global config returned by GetConfig,
+// and Watcher reloads config from the same sources.
+func Load(configPath string, flags *pflag.FlagSet) (*Config, error) {
+	cfg, err := load(configPath, flags)
+	if err != nil {
+		return nil, err
+	}
+	globalCfg = cfg
+	sources.path = configPath
+	sources.flags = flags
+	return cfg, nil
+}
+
+// sources the config loaded from, used for reloading
+var sources struct {
+	path  string
+	flags *pflag.FlagSet
+}
+
+func load(configPath string, flags *pflag.FlagSet) (*Config, error) {
+	if configPath != "" && !utils.IsFileExist(configPath) {
+		return nil, errors.Errorf("config file %s not found", configPath)
+	}
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyEnv(cfg, os.Environ()); err != nil {
+		return nil, err
+	}
+	if flags != nil {
+		if err := applyFlags(cfg, flags); err != nil {
+			return nil, err
+		}
+	}
+	fixConfigDirs(cfg)
+	return cfg, nil
+}
+
+// decodeRaw decodes yaml or toml data into a map, returns nil map for json data
+func decodeRaw(configPath string, data []byte) (map[string]interface{}, error) {
+	raw := map[string]interface{}{}
+	switch ext := strings.ToLower(filepath.Ext(configPath)); ext {
+	case ".json", "":
+		return nil, nil
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	case ".toml":
+		if err := toml.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("unsupported config format %s", ext)
+	}
+	return raw, nil
+}
+
+// decodeFile decodes data into cfg according to the file extension,
+// only keys present in data override fields of cfg
+func decodeFile(cfg *Config, configPath string, data []byte) error {
+	raw, err := decodeRaw(configPath, data)
+	if err != nil {
+		return err
+	}
+	if raw != nil {
+		// json tags are the single source of field names for all formats
+		if data, err = json.Marshal(raw); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, cfg)
+}
+
+// field is a leaf field of Config addressed by its json key path
+type field struct {
+	key   string
+	value reflect.Value
+}
+
+// walkFields lists leaf fields of v, keys are json tags joined with "."
+func walkFields(v reflect.Value, prefix string) []field {
+	var fields []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || !sf.IsExported() {
+			continue
+		}
+		key := prefix + name
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			fields = append(fields, walkFields(fv, key+".")...)
+			continue
+		}
+		fields = append(fields, field{key: key, value: fv})
+	}
+	return fields
+}
+
+// lookupField finds the leaf field of cfg by json key path like "redis.address"
+func lookupField(cfg *Config, key string) (reflect.Value, bool) {
+	for _, f := range walkFields(reflect.ValueOf(cfg).Elem(), "") {
+		if f.key == key {
+			return f.value, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// setField parses s into v according to kind of v,
+// slices are parsed from comma separated values, maps from comma separated key=value
+func setField(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		if v.Type() != reflect.TypeOf(map[string]int{}) {
+			return fmt.Errorf("unsupported map type %s", v.Type())
+		}
+		m := map[string]int{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			k, val, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("%q is not key=value", item)
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(val))
+			if err != nil {
+				return err
+			}
+			m[strings.TrimSpace(k)] = n
+		}
+		v.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+// fieldString formats v in the form accepted by setField
+func fieldString(v reflect.Value) string {
+	switch x := v.Interface().(type) {
+	case []string:
+		return strings.Join(x, ",")
+	case map[string]int:
+		items := make([]string, 0, len(x))
+		for k, n := range x {
+			items = append(items, fmt.Sprintf("%s=%d", k, n))
+		}
+		sort.Strings(items)
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// EnvName returns environment variable name of config key, e.g. redis.address -> APP_REDIS_ADDRESS
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// FlagName returns command line flag name of config key, e.g. redis.address -> redis-address
+func FlagName(key string) string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(key)
+}
+
+// applyEnv overrides fields of cfg by environment variables in "key=value" form
+func applyEnv(cfg *Config, environ []string) error {
+	envs := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, EnvPrefix) {
+			envs[k] = v
+		}
+	}
+	for _, f := range walkFields(reflect.ValueOf(cfg).Elem(), "") {
+		name := EnvName(f.key)
+		if v, exists := envs[name]; exists {
+			if err := setField(f.value, v); err != nil {
+				return errors.Wrapf(err, "invalid value of env %s", name)
+			}
+		}
+	}
+	return nil
+}
+
+// RegisterFlags registers flags overriding the config fields of keys to flags,
+// keys are json key paths like "server_port" or "redis.address".
+// It panics on unknown key.
+func RegisterFlags(flags *pflag.FlagSet, keys ...string) {
+	defaults := DefaultConfig()
+	for _, key := range keys {
+		v, ok := lookupField(defaults, key)
+		if !ok {
+			panic(fmt.Sprintf("unknown config key %s", key))
+		}
+		name := FlagName(key)
+		usage := fmt.Sprintf("override config %s (env %s)", key, EnvName(key))
+		if v.Kind() == reflect.Bool {
+			flags.Bool(name, v.Bool(), usage)
+		} else {
+			flags.String(name, fieldString(v), usage)
+		}
+		_ = flags.SetAnnotation(name, flagKeyAnnotation, []string{key})
+	}
+}
+
+// applyFlags overrides fields of cfg by flags registered with RegisterFlags
+// and explicitly set on command line
+func applyFlags(cfg *Config, flags *pflag.FlagSet) error {
+	var err error
+	flags.Visit(func(f *pflag.Flag) {
+		keys := f.Annotations[flagKeyAnnotation]
+		if err != nil || len(keys) == 0 {
+			return
+		}
+		v, ok := lookupField(cfg, keys[0])
+		if !ok {
+			return
+		}
+		if setErr := setField(v, f.Value.String()); setErr != nil {
+			err = errors.Wrapf(setErr, "invalid value of flag --%s", f.Name)
+		}
+	})
+	return err
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadFromPathFormats(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{"server_port": "9000", "redis": {"address": "127.0.0.1:6379"}}`,
+		"config.yaml": "server_port: \"9000\"\nredis:\n  address: 127.0.0.1:6379\n",
+		"config.toml": "server_port = \"9000\"\n[redis]\naddress = \"127.0.0.1:6379\"\n",
+	}
+	for name, content := range files {
+		cfg, err := LoadFromPath(writeFile(t, name, content))
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if cfg.ServerPort != "9000" || cfg.Redis.Address != "127.0.0.1:6379" {
+			t.Error(name, cfg.ServerPort, cfg.Redis.Address)
+		}
+		// fields absent in file keep default values
+		if cfg.DBConnectionPoolSize != DefaultConfig().DBConnectionPoolSize {
+			t.Error(name, "default value overridden", cfg.DBConnectionPoolSize)
+		}
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	p := writeFile(t, "config.json", `{"server_port": "9000", "jwt_secret": "file", "debug": false}`)
+	t.Setenv("APP_SERVER_PORT", "9001")
+	t.Setenv("APP_JWT_SECRET", "env")
+	t.Setenv("APP_REDIS_DB", "3")
+	t.Setenv("APP_ERRORS_STATUS_CODES", "6=422, 1=400")
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	RegisterFlags(flags, "server_port", "debug")
+	if err := flags.Parse([]string{"--server-port", "9002", "--debug"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(p, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServerPort != "9002" {
+		t.Error("flag should override env", cfg.ServerPort)
+	}
+	if cfg.JWTSecret != "env" {
+		t.Error("env should override file", cfg.JWTSecret)
+	}
+	if cfg.Redis.DB != 3 {
+		t.Error("nested env not applied", cfg.Redis.DB)
+	}
+	if !cfg.Debug {
+		t.Error("bool flag not applied")
+	}
+	if codes := cfg.Errors.StatusCodes; len(codes) != 2 || codes["6"] != 422 {
+		t.Error("map env not applied", codes)
+	}
+	if GetConfig() != cfg {
+		t.Error("global config not replaced")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json"), nil); err == nil {
+		t.Error("missing config file should fail")
+	}
+	t.Setenv("APP_DB_CONNECTION_POOL_SIZE", "many")
+	if _, err := Load("", nil); err == nil {
+		t.Error("invalid env value should fail")
+	}
+}
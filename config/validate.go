@@ -0,0 +1,300 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/go-sql-driver/mysql"
+	"go.uber.org/fx"
+)
+
+// requiresGroup is the fx value group of Require
+const requiresGroup = "config_requires"
+
+// Require checks config fields required by a sub command, returns problems found
+type Require func(cfg *Config) []string
+
+// ValidationError aggregates all problems of config, its cause is errors.ConfigError
+type ValidationError struct {
+	Problems []string
+}
+
+// Error ...
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d problem(s) found:", errors.ConfigError, len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(p)
+	}
+	return b.String()
+}
+
+// Cause ...
+func (e *ValidationError) Cause() error {
+	return errors.ConfigError
+}
+
+// Validate checks common fields and fields of requires,
+// returns *ValidationError listing all problems if any
+func (c *Config) Validate(requires ...Require) error {
+	var problems []string
+	if c.ServerPort != "" {
+		if port, err := strconv.Atoi(c.ServerPort); err != nil || port <= 0 || port > 65535 {
+			problems = append(problems, fmt.Sprintf("server_port: %q is not a valid port", c.ServerPort))
+		}
+	}
+	if c.MetricsPath != "" && !strings.HasPrefix(c.MetricsPath, "/") {
+		problems = append(problems, fmt.Sprintf("metrics_path: %q must start with /", c.MetricsPath))
+	}
+	if c.DBConnectionPoolSize < 0 {
+		problems = append(problems, fmt.Sprintf("db_connection_pool_size: must not be negative, got %d", c.DBConnectionPoolSize))
+	}
+	if c.ShutdownTimeout < 0 {
+		problems = append(problems, fmt.Sprintf("shutdown_timeout: must not be negative, got %d", c.ShutdownTimeout))
+	}
+	problems = append(problems, validateCors("cors", c.Cors, c.Cors.AllowCredentials)...)
+	for prefix, o := range c.Cors.Groups {
+		key := "cors.groups." + prefix
+		if !strings.HasPrefix(prefix, "/") {
+			problems = append(problems, fmt.Sprintf("%s: prefix must start with /", key))
+		}
+		// fields inherited are checked as fields of cors
+		overrides := CorsConfig{AllowedOrigins: o.AllowedOrigins, AllowedMethods: o.AllowedMethods, MaxAge: o.MaxAge}
+		problems = append(problems, validateCors(key, overrides, c.Cors.Group(prefix).AllowCredentials)...)
+	}
+	switch c.Security.FrameOptions {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		problems = append(problems, fmt.Sprintf("security.frame_options: must be DENY or SAMEORIGIN, got %q", c.Security.FrameOptions))
+	}
+	if c.Security.HSTSMaxAge < 0 {
+		problems = append(problems, fmt.Sprintf("security.hsts_max_age: must not be negative, got %d", c.Security.HSTSMaxAge))
+	}
+	if c.Security.MaxBodyBytes < 0 {
+		problems = append(problems, fmt.Sprintf("security.max_body_bytes: must not be negative, got %d", c.Security.MaxBodyBytes))
+	}
+	if c.Security.MaxHeaderBytes < 0 {
+		problems = append(problems, fmt.Sprintf("security.max_header_bytes: must not be negative, got %d", c.Security.MaxHeaderBytes))
+	}
+	for _, proxy := range c.Security.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				problems = append(problems, fmt.Sprintf("security.trusted_proxies: %q is not an ip or cidr", proxy))
+			}
+		}
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		problems = append(problems, "tls: cert_file and key_file must be set together")
+	}
+	if !c.TLS.Enabled() && (c.TLS.ClientCAFile != "" || c.TLS.RedirectPort != "") {
+		problems = append(problems, "tls: client_ca_file and redirect_port require cert_file")
+	}
+	switch c.TLS.ClientAuth {
+	case ClientAuthRequire, ClientAuthOptional:
+	default:
+		problems = append(problems, fmt.Sprintf("tls.client_auth: must be require or optional, got %q", c.TLS.ClientAuth))
+	}
+	if c.TLS.RedirectPort != "" && c.TLS.RedirectPort == c.ListenPort() {
+		problems = append(problems, "tls.redirect_port: must differ from server_port")
+	}
+	switch c.RateLimit.Backend {
+	case RateLimitBackendNone, RateLimitBackendMemory:
+	case RateLimitBackendRedis:
+		if c.Redis.Address == "" {
+			problems = append(problems, "rate_limit.backend: redis requires redis.address")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("rate_limit.backend: unknown backend %q", c.RateLimit.Backend))
+	}
+	for group, limit := range c.RateLimit.Limits {
+		if limit.Requests < 0 {
+			problems = append(problems, fmt.Sprintf("rate_limit.limits.%s.requests: must not be negative, got %d", group, limit.Requests))
+		}
+		if limit.Requests > 0 && limit.Period <= 0 {
+			problems = append(problems, fmt.Sprintf("rate_limit.limits.%s.period: must be positive, got %d", group, limit.Period))
+		}
+	}
+	if c.Health.Timeout <= 0 {
+		problems = append(problems, fmt.Sprintf("health.timeout: must be positive, got %d", c.Health.Timeout))
+	}
+	if c.Health.CacheTTL < 0 {
+		problems = append(problems, fmt.Sprintf("health.cache_ttl: must not be negative, got %d", c.Health.CacheTTL))
+	}
+	if c.Health.ShutdownDelay < 0 {
+		problems = append(problems, fmt.Sprintf("health.shutdown_delay: must not be negative, got %d", c.Health.ShutdownDelay))
+	}
+	if c.Redis.Address != "" {
+		if _, _, err := net.SplitHostPort(c.Redis.Address); err != nil {
+			problems = append(problems, fmt.Sprintf("redis.address: %q is not host:port", c.Redis.Address))
+		}
+	}
+	if c.Redis.PoolSize < 0 {
+		problems = append(problems, fmt.Sprintf("redis.pool_size: must not be negative, got %d", c.Redis.PoolSize))
+	}
+	switch c.Cache.Backend {
+	case CacheBackendNone, CacheBackendMemory:
+	case CacheBackendRedis:
+		if c.Redis.Address == "" {
+			problems = append(problems, "cache.backend: redis requires redis.address")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("cache.backend: unknown backend %q", c.Cache.Backend))
+	}
+	if c.Cache.TTL < 0 {
+		problems = append(problems, fmt.Sprintf("cache.ttl: must not be negative, got %d", c.Cache.TTL))
+	}
+	if c.JWT.AccessTTL <= 0 {
+		problems = append(problems, fmt.Sprintf("jwt.access_ttl: must be positive, got %d", c.JWT.AccessTTL))
+	}
+	if c.JWT.RefreshTTL <= 0 {
+		problems = append(problems, fmt.Sprintf("jwt.refresh_ttl: must be positive, got %d", c.JWT.RefreshTTL))
+	}
+	switch c.JWT.RevocationStore {
+	case RevocationStoreMemory:
+	case RevocationStoreRedis:
+		if c.Redis.Address == "" {
+			problems = append(problems, "jwt.revocation_store: redis requires redis.address")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("jwt.revocation_store: unknown store %q", c.JWT.RevocationStore))
+	}
+	if len(c.OIDC.Providers) > 0 {
+		if u, err := url.Parse(c.OIDC.RedirectBaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("oidc.redirect_base_url: %q is not an absolute url", c.OIDC.RedirectBaseURL))
+		}
+	}
+	for name, p := range c.OIDC.Providers {
+		if p.Issuer == "" || p.ClientID == "" {
+			problems = append(problems, fmt.Sprintf("oidc.providers: issuer and client_id of %s are required", name))
+		}
+	}
+	for code, status := range c.Errors.StatusCodes {
+		if _, err := strconv.Atoi(code); err != nil {
+			problems = append(problems, fmt.Sprintf("errors.status_codes: %q is not an error code", code))
+		}
+		if status < 400 || status > 599 {
+			problems = append(problems, fmt.Sprintf("errors.status_codes: status %d of code %s is not an error status", status, code))
+		}
+	}
+	switch c.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterStdout, TracingExporterOTLP:
+	default:
+		problems = append(problems, fmt.Sprintf("tracing.exporter: unknown exporter %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, fmt.Sprintf("tracing.sample_ratio: must be between 0 and 1, got %v", c.Tracing.SampleRatio))
+	}
+	for _, require := range requires {
+		problems = append(problems, require(c)...)
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// RequireDB requires main_db to be valid mysql dsn(s), one per line, replicas follow the primary
+func RequireDB(cfg *Config) []string {
+	if strings.TrimSpace(cfg.MainDB) == "" {
+		return []string{"main_db: required"}
+	}
+	var problems []string
+	for i, dsn := range strings.Split(cfg.MainDB, "\n") {
+		if dsn == "" {
+			continue
+		}
+		if _, err := mysql.ParseDSN(dsn); err != nil {
+			problems = append(problems, fmt.Sprintf("main_db: line %d is invalid: %s", i+1, err))
+		}
+	}
+	return problems
+}
+
+// RequireJWTSecret requires jwt_secret, or jwt.keys_dir to be a directory
+func RequireJWTSecret(cfg *Config) []string {
+	if cfg.JWT.KeysDir != "" {
+		if info, err := os.Stat(cfg.JWT.KeysDir); err != nil || !info.IsDir() {
+			return []string{fmt.Sprintf("jwt.keys_dir: %s is not a directory", cfg.JWT.KeysDir)}
+		}
+		return nil
+	}
+	if cfg.JWTSecret == "" {
+		return []string{"jwt_secret: required unless jwt.keys_dir is set"}
+	}
+	return nil
+}
+
+// validateCors checks policy of key, credentials is allow_credentials in effect for the policy
+func validateCors(key string, policy CorsConfig, credentials bool) []string {
+	var problems []string
+	for _, origin := range policy.AllowedOrigins {
+		if origin == "*" {
+			if credentials {
+				problems = append(problems, fmt.Sprintf("%s.allow_credentials: not allowed with origin *", key))
+			}
+			continue
+		}
+		// wildcard is matched by prefix and suffix, so it's only allowed as the whole leading label
+		// e.g. https://*.example.com, otherwise https://*example.com allows https://evilexample.com
+		u, err := url.Parse(strings.Replace(origin, "://*.", "://", 1))
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" || strings.Contains(u.Host, "*") ||
+			(strings.Contains(origin, "*") && !strings.Contains(u.Hostname(), ".")) {
+			problems = append(problems, fmt.Sprintf(
+				"%s.allowed_origins: %q is not an origin like https://app.example.com or https://*.example.com", key, origin))
+		}
+	}
+	for _, method := range policy.AllowedMethods {
+		if method == "" || strings.ToUpper(method) != method {
+			problems = append(problems, fmt.Sprintf("%s.allowed_methods: %q is not an upper case method", key, method))
+		}
+	}
+	if policy.MaxAge < 0 {
+		problems = append(problems, fmt.Sprintf("%s.max_age: must not be negative, got %d", key, policy.MaxAge))
+	}
+	return problems
+}
+
+// RequirePublicDir requires public_dir to be a readable directory
+func RequirePublicDir(cfg *Config) []string {
+	f, err := os.Open(cfg.PublicDir)
+	if err != nil {
+		return []string{fmt.Sprintf("public_dir: %s", err)}
+	}
+	defer f.Close()
+	if _, err := f.ReadDir(1); err != nil && err != io.EOF {
+		return []string{fmt.Sprintf("public_dir: %s is not a readable directory: %s", cfg.PublicDir, err)}
+	}
+	return nil
+}
+
+// Requires adds requires to be checked by validation of Module
+func Requires(requires ...Require) fx.Option {
+	opts := make([]fx.Option, 0, len(requires))
+	for _, require := range requires {
+		require := require
+		opts = append(opts, fx.Provide(fx.Annotated{
+			Group:  requiresGroup,
+			Target: func() Require { return require },
+		}))
+	}
+	return fx.Options(opts...)
+}
+
+type validateParams struct {
+	fx.In
+
+	Config   *Config
+	Requires []Require `group:"config_requires"`
+}
+
+// validate fails app start with all problems of config
+func validate(p validateParams) error {
+	return p.Config.Validate(p.Requires...)
+}
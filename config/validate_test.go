@@ -0,0 +1,79 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/dean2032/go-project-layout/utils/errors"
+)
+
+func TestValidateDefault(t *testing.T) {
+	if err := DefaultConfig().Validate(RequirePublicDir); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateAggregates(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ServerPort = "port"
+	cfg.DBConnectionPoolSize = -1
+	cfg.MainDB = ""
+	cfg.PublicDir = "/path/not/exist"
+
+	err := cfg.Validate(RequireDB, RequireJWTSecret, RequirePublicDir)
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(verr.Problems) != 5 {
+		t.Error(verr.Problems)
+	}
+	if !errors.IsCodeErrorEqual(err, errors.ConfigError) {
+		t.Error("cause should be ConfigError")
+	}
+}
+
+func TestValidateRedirectPort(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TLS.CertFile, cfg.TLS.KeyFile = "tls.crt", "tls.key"
+	cfg.ServerPort = ""
+	cfg.TLS.RedirectPort = DefaultServerPort
+	if err := cfg.Validate(); err == nil {
+		t.Error("redirect port of the default server port should be rejected")
+	}
+	cfg.TLS.RedirectPort = "8081"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRequireDB(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MainDB = "user:pass@tcp(127.0.0.1:3306)/db\nuser:pass@tcp(127.0.0.2:3306)/db\n"
+	if problems := RequireDB(cfg); len(problems) != 0 {
+		t.Error(problems)
+	}
+	cfg.MainDB += "invalid"
+	if problems := RequireDB(cfg); len(problems) != 1 {
+		t.Error(problems)
+	}
+}
+
+func TestValidateCors(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Cors.AllowedOrigins = []string{"https://app.example.com", "https://*.example.com", "example.com", "https://*.*.com",
+		"https://*example.com", "https://app.*", "https://*.com", "http://*.example.com:8080"}
+	cfg.Cors.AllowedMethods = []string{"get"}
+	credentials := true
+	cfg.Cors.Groups = map[string]CorsOverride{
+		"/public": {AllowedOrigins: []string{"*"}, AllowCredentials: &credentials},
+	}
+	err := cfg.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// 5 origins and method of policy, credentials with * of group
+	if len(verr.Problems) != 7 {
+		t.Error(verr.Problems)
+	}
+}
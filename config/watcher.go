@@ -0,0 +1,222 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// reloadableKeys config keys can be applied without restart,
+// changes of other keys are ignored with a warning until restart
+var reloadableKeys = map[string]bool{
+	"log_level":            true,
+	"slow_threshold":       true,
+	"pprof_path":           true,
+	"sentry_dsn":           true,
+	"cors.allowed_origins": true,
+	"cors.groups":          true,
+	"rate_limit.limits":    true,
+	"errors.status_codes":  true,
+	"errors.problem_json":  true,
+}
+
+// reloadDebounce merges file events in a short time into one reload,
+// editors and kubernetes configmap updates usually emit several events
+const reloadDebounce = 500 * time.Millisecond
+
+// Subscriber is notified after reloadable fields of config changed,
+// prev is the config before reload, cur is the new one, both are snapshots which must not be modified
+type Subscriber func(prev, cur *Config)
+
+// Watcher reloads config on SIGHUP and change of config file,
+// then publishes a new config with changes of reloadable fields applied.
+// The global config is never modified, so it's safe to read without locks
+type Watcher struct {
+	current     atomic.Pointer[Config]
+	mu          sync.Mutex
+	subscribers []Subscriber
+	done        chan struct{}
+}
+
+// newWatcher creates a watcher publishing cfg as current config
+func newWatcher(cfg *Config) *Watcher {
+	w := &Watcher{done: make(chan struct{})}
+	w.current.Store(cfg)
+	return w
+}
+
+// NewWatcher creates a watcher of the global config, it starts watching on app start
+func NewWatcher(lc fx.Lifecycle, cfg *Config) *Watcher {
+	w := newWatcher(cfg)
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go w.watch()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			close(w.done)
+			return nil
+		},
+	})
+	return w
+}
+
+// Current returns the latest config, with reloaded fields applied
+func (w *Watcher) Current() *Config {
+	return w.current.Load()
+}
+
+// Subscribe registers fn to be notified after config reloaded
+func (w *Watcher) Subscribe(fn Subscriber) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+func (w *Watcher) watch() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	path := filepath.Clean(sources.path)
+	// target of symlink path, kubernetes configmaps replace files by swapping symlinks of their directory
+	target, _ := filepath.EvalSymlinks(path)
+	var events chan fsnotify.Event
+	var errs chan error
+	if sources.path != "" {
+		fw, err := fsnotify.NewWatcher()
+		if err != nil {
+			logging.Error("create config file watcher fail", zap.Error(err))
+		} else {
+			defer fw.Close()
+			// watch the directory, the file may be replaced by rename or symlink swap
+			if err := fw.Add(filepath.Dir(sources.path)); err != nil {
+				logging.Error("watch config file fail", zap.String("path", sources.path), zap.Error(err))
+			}
+			events, errs = fw.Events, fw.Errors
+		}
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-hup:
+			logging.Info("received SIGHUP, reloading config")
+			w.Reload()
+		case ev := <-events:
+			if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+				continue
+			}
+			// other files of the directory are ignored unless they swapped target of the config file
+			if filepath.Clean(ev.Name) != path {
+				cur, _ := filepath.EvalSymlinks(path)
+				if cur == target {
+					continue
+				}
+				target = cur
+			}
+			debounce = time.After(reloadDebounce)
+		case err := <-errs:
+			// errors must be received, fsnotify blocks reading events until then
+			logging.Error("config file watcher error", zap.String("path", sources.path), zap.Error(err))
+		case <-debounce:
+			debounce = nil
+			if _, err := os.Stat(sources.path); err != nil {
+				// file is being replaced, wait for next event
+				continue
+			}
+			logging.Info("config file changed, reloading config", zap.String("path", sources.path))
+			w.Reload()
+		}
+	}
+}
+
+// Reload loads config from the sources of Load and applies changes of reloadable fields.
+// Invalid config is rejected as a whole.
+func (w *Watcher) Reload() {
+	next, err := load(sources.path, sources.flags)
+	if err == nil {
+		err = next.Validate()
+	}
+	if err != nil {
+		logging.Error("reload config fail, keep current config", zap.Error(err))
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// reloaded fields are applied to a copy, readers of the current config never see partial changes
+	prev := w.Current()
+	cur := *prev
+	changed := false
+	nextFields := walkFields(reflect.ValueOf(next).Elem(), "")
+	for i, f := range walkFields(reflect.ValueOf(&cur).Elem(), "") {
+		nv := nextFields[i].value
+		if reflect.DeepEqual(f.value.Interface(), nv.Interface()) {
+			continue
+		}
+		if !reloadableKeys[f.key] {
+			logging.Warn("config changed but requires restart to apply", zap.String("key", f.key))
+			continue
+		}
+		logging.Info("config changed", zap.String("key", f.key),
+			zap.String("from", fieldString(f.value)), zap.String("to", fieldString(nv)))
+		f.value.Set(nv)
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	w.current.Store(&cur)
+	for _, fn := range w.subscribers {
+		fn(prev, &cur)
+	}
+}
+
+// logLevel returns LogLevel, or level of logger initialized by Debug if it's not set
+func (c *Config) logLevel() string {
+	switch {
+	case c.LogLevel != "":
+		return c.LogLevel
+	case c.Debug:
+		return "debug"
+	default:
+		return "info"
+	}
+}
+
+// setupLogging applies logging related config and keeps them updated
+func setupLogging(cfg *Config, w *Watcher) {
+	if cfg.LogLevel != "" {
+		logging.SetLevel(cfg.LogLevel)
+	}
+	if cfg.SentryDSN != "" {
+		if err := logging.SetSentryDSN(cfg.SentryDSN, cfg.Debug); err != nil {
+			logging.Error("set sentry dsn fail", zap.Error(err))
+		}
+	}
+	w.Subscribe(func(prev, cur *Config) {
+		if cur.LogLevel != prev.LogLevel {
+			logging.SetLevel(cur.logLevel())
+		}
+		if cur.SentryDSN != prev.SentryDSN {
+			if err := logging.SetSentryDSN(cur.SentryDSN, cur.Debug); err != nil {
+				logging.Error("set sentry dsn fail", zap.Error(err))
+			}
+		}
+	})
+}
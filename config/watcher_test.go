@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/dean2032/go-project-layout/utils/logging"
+)
+
+func TestWatcherReload(t *testing.T) {
+	p := writeFile(t, "config.yaml", "server_port: \"9000\"\nslow_threshold: 3\n")
+	cfg, err := Load(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := newWatcher(cfg)
+	notified := 0
+	w.Subscribe(func(prev, cur *Config) {
+		notified++
+		if prev.SlowThreshold != 3 || cur.SlowThreshold != 5 {
+			t.Error("unexpected change", prev.SlowThreshold, cur.SlowThreshold)
+		}
+	})
+
+	content := "server_port: \"9001\"\nslow_threshold: 5\ncors:\n  allowed_origins: [\"https://example.com\"]\n"
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.Reload()
+	if notified != 1 {
+		t.Fatal("subscriber not notified")
+	}
+	cur := w.Current()
+	if cur.ServerPort != "9000" {
+		t.Error("server_port requires restart, should not be applied", cur.ServerPort)
+	}
+	if len(cur.Cors.AllowedOrigins) != 1 {
+		t.Error("cors origins not applied", cur.Cors.AllowedOrigins)
+	}
+	if cfg.SlowThreshold != 3 || len(cfg.Cors.AllowedOrigins) != 0 {
+		t.Error("loaded config should not be modified by reload")
+	}
+
+	// invalid config is rejected as a whole
+	if err := os.WriteFile(p, []byte("slow_threshold: 7\nshutdown_timeout: -1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.Reload()
+	if notified != 1 || w.Current().SlowThreshold != 5 {
+		t.Error("invalid config should not be applied")
+	}
+}
+
+func TestWatcherLogLevel(t *testing.T) {
+	defer logging.SetLevel(logging.TextLevel())
+	p := writeFile(t, "config.yaml", "log_level: warn\n")
+	cfg, err := Load(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := newWatcher(cfg)
+	setupLogging(cfg, w)
+	if logging.TextLevel() != "warn" {
+		t.Fatal("log_level not applied", logging.TextLevel())
+	}
+
+	// unset log_level restores the level by debug
+	if err := os.WriteFile(p, []byte("log_level: \"\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.Reload()
+	if logging.TextLevel() != "info" {
+		t.Error("level by debug should be restored", logging.TextLevel())
+	}
+}
+
+func TestWatcherReloadErrors(t *testing.T) {
+	p := writeFile(t, "config.yaml", "errors:\n  problem_json: false\n")
+	cfg, err := Load(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := newWatcher(cfg)
+	var applied ErrorsConfig
+	w.Subscribe(func(prev, cur *Config) { applied = cur.Errors })
+
+	content := "errors:\n  problem_json: true\n  status_codes: {\"6\": 422}\n"
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.Reload()
+	if !applied.ProblemJSON || applied.StatusCodes["6"] != 422 {
+		t.Error("errors should be reloaded", applied)
+	}
+}
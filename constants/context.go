@@ -4,6 +4,9 @@ const (
 	// DBTransaction is database transaction handle set at router context
 	DBTransaction = "db_tx"
 
-	// ErrorCodeGinContextKey is key for error code of gin context
+	// Principal is the authenticated caller set at router context
+	Principal = "principal"
+
+	// ErrorCodeGinContextKey is key for *errors.CodeError of error response set at router context
 	ErrorCodeGinContextKey = "error_code"
 )
@@ -0,0 +1,8 @@
+package constants
+
+const (
+	// ScopeUsersRead permission to read users
+	ScopeUsersRead = "users:read"
+	// ScopeUsersWrite permission to create, update and delete users
+	ScopeUsersWrite = "users:write"
+)
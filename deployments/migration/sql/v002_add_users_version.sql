@@ -0,0 +1,6 @@
+
+-- +migrate Up
+ALTER TABLE `users` ADD COLUMN `version` INT(10) UNSIGNED NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE `users` DROP COLUMN `version`;
@@ -0,0 +1,6 @@
+
+-- +migrate Up
+ALTER TABLE `users` ADD COLUMN `password_hash` VARCHAR(100) NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE `users` DROP COLUMN `password_hash`;
@@ -0,0 +1,19 @@
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS `refresh_tokens` (
+  `id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+  `user_id` INT(10) UNSIGNED NOT NULL,
+  `family_id` VARCHAR(32) NOT NULL,
+  `token_hash` CHAR(64) NOT NULL,
+  `expires_at` DATETIME NOT NULL,
+  `used_at` DATETIME,
+  `revoked_at` DATETIME,
+  `created_at` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  CONSTRAINT token_hash_unique UNIQUE(token_hash),
+  INDEX family_id_index (family_id),
+  INDEX user_id_index (user_id)
+)ENGINE = InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +migrate Down
+DROP TABLE IF EXISTS `refresh_tokens`;
@@ -0,0 +1,42 @@
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS `roles` (
+  `id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+  `name` VARCHAR(50) NOT NULL,
+  `description` VARCHAR(200) NOT NULL DEFAULT '',
+  `created_at` DATETIME NOT NULL,
+  `updated_at` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  CONSTRAINT name_unique UNIQUE(name)
+)ENGINE = InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `role_permissions` (
+  `role_id` INT(10) UNSIGNED NOT NULL,
+  `permission` VARCHAR(100) NOT NULL,
+  PRIMARY KEY (`role_id`, `permission`)
+)ENGINE = InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_roles` (
+  `user_id` INT(10) UNSIGNED NOT NULL,
+  `role_id` INT(10) UNSIGNED NOT NULL,
+  PRIMARY KEY (`user_id`, `role_id`),
+  INDEX role_id_index (role_id)
+)ENGINE = InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT INTO `roles` (`name`, `description`, `created_at`, `updated_at`) VALUES
+  ('admin', 'manage users', NOW(), NOW()),
+  ('member', 'default role of registered users', NOW(), NOW());
+
+INSERT INTO `role_permissions` (`role_id`, `permission`)
+  SELECT `id`, 'users:read' FROM `roles` WHERE `name` IN ('admin', 'member')
+  UNION ALL
+  SELECT `id`, 'users:write' FROM `roles` WHERE `name` = 'admin';
+
+-- existing users keep read access
+INSERT INTO `user_roles` (`user_id`, `role_id`)
+  SELECT `users`.`id`, `roles`.`id` FROM `users`, `roles` WHERE `roles`.`name` = 'member';
+
+-- +migrate Down
+DROP TABLE IF EXISTS `user_roles`;
+DROP TABLE IF EXISTS `role_permissions`;
+DROP TABLE IF EXISTS `roles`;
@@ -0,0 +1,20 @@
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS `api_keys` (
+  `id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+  `name` VARCHAR(100) NOT NULL,
+  `owner` VARCHAR(100) NOT NULL,
+  `prefix` VARCHAR(16) NOT NULL,
+  `key_hash` CHAR(64) NOT NULL,
+  `scopes` VARCHAR(500) NOT NULL DEFAULT '',
+  `expires_at` DATETIME,
+  `last_used_at` DATETIME,
+  `revoked_at` DATETIME,
+  `created_at` DATETIME NOT NULL,
+  `updated_at` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  CONSTRAINT key_hash_unique UNIQUE(key_hash)
+)ENGINE = InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +migrate Down
+DROP TABLE IF EXISTS `api_keys`;
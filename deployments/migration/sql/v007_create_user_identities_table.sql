@@ -0,0 +1,17 @@
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS `user_identities` (
+  `id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+  `user_id` INT(10) UNSIGNED NOT NULL,
+  `provider` VARCHAR(50) NOT NULL,
+  `subject` VARCHAR(255) NOT NULL,
+  `email` VARCHAR(100) NOT NULL DEFAULT '',
+  `created_at` DATETIME NOT NULL,
+  `updated_at` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  CONSTRAINT provider_subject_unique UNIQUE(provider, subject),
+  INDEX user_id_index (user_id)
+)ENGINE = InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +migrate Down
+DROP TABLE IF EXISTS `user_identities`;
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/dean2032/go-project-layout/cmd"
 	_ "github.com/go-sql-driver/mysql"
 )
@@ -13,5 +15,7 @@ var (
 
 func main() {
 	cmd.SetAppVersion(BuildVersion, GitCommit, BuildTime)
-	cmd.RootApp.Execute()
+	if err := cmd.RootApp.Execute(); err != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// APIKey model of service credentials, only sha256 of key is stored
+type APIKey struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	// Owner service or team responsible for the key
+	Owner string `json:"owner"`
+	// Prefix leading characters of key to identify it
+	Prefix  string `json:"prefix"`
+	KeyHash string `json:"-"`
+	// Scopes space separated scopes granted to the key
+	Scopes string `json:"scopes"`
+	// ExpiresAt nil if the key never expires
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// TableName gives table name of model
+func (k APIKey) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// RefreshToken model, only sha256 of token is stored
+type RefreshToken struct {
+	ID     uint
+	UserID uint
+	// FamilyID identifies tokens rotated from the same sign in
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	// UsedAt is set when the token is rotated, using it again revokes the family
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// TableName gives table name of model
+func (t RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
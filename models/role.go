@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Role model, permissions of role are granted to its users as scopes of token
+type Role struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName gives table name of model
+func (r Role) TableName() string {
+	return "roles"
+}
+
+// RolePermission model
+type RolePermission struct {
+	RoleID     uint   `json:"role_id"`
+	Permission string `json:"permission"`
+}
+
+// TableName gives table name of model
+func (p RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+// UserRole model
+type UserRole struct {
+	UserID uint `json:"user_id"`
+	RoleID uint `json:"role_id"`
+}
+
+// TableName gives table name of model
+func (r UserRole) TableName() string {
+	return "user_roles"
+}
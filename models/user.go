@@ -15,6 +15,8 @@ type User struct {
 	MemberNumber sql.NullString `json:"member_number"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
+	Version      uint           `json:"version"`
+	PasswordHash string         `json:"-"`
 }
 
 // TableName gives table name of model
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// UserIdentity external identity of user at OpenID Connect provider
+type UserIdentity struct {
+	ID     uint `json:"id"`
+	UserID uint `json:"user_id"`
+	// Provider name of provider in config
+	Provider string `json:"provider"`
+	// Subject sub claim of id token, unique at provider
+	Subject string `json:"subject"`
+	// Email email at provider when linked
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName gives table name of model
+func (i UserIdentity) TableName() string {
+	return "user_identities"
+}
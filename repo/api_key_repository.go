@@ -0,0 +1,13 @@
+package repo
+
+// APIKeyRepository database of api keys
+type APIKeyRepository struct {
+	*Database
+}
+
+// NewAPIKeyRepository creates a new api key repository
+func NewAPIKeyRepository(db *Database) *APIKeyRepository {
+	return &APIKeyRepository{
+		Database: db,
+	}
+}
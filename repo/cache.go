@@ -0,0 +1,25 @@
+package repo
+
+import (
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/cache"
+	"github.com/dean2032/go-project-layout/utils/logging"
+)
+
+// cacheKeyPrefix prefix of keys stored in redis by cache
+const cacheKeyPrefix = "cache:"
+
+// NewCache creates cache backend of cfg.Cache.Backend
+func NewCache(cfg *config.Config, redis *Redis) cache.Cache {
+	switch cfg.Cache.Backend {
+	case config.CacheBackendRedis:
+		logging.Info("using redis cache")
+		return cache.NewRedisCache(redis.Client, cacheKeyPrefix)
+	case config.CacheBackendMemory:
+		logging.Infof("using memory cache of size %d", cfg.Cache.Size)
+		return cache.NewMemoryCache(cfg.Cache.Size)
+	default:
+		logging.Info("cache disabled")
+		return cache.NewNopCache()
+	}
+}
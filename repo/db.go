@@ -1,6 +1,9 @@
 package repo
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"log"
 	"os"
 	"strings"
@@ -8,7 +11,13 @@ import (
 
 	"github.com/dean2032/go-project-layout/config"
 	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/health"
 	"github.com/dean2032/go-project-layout/utils/logging"
+	"github.com/dean2032/go-project-layout/utils/metrics"
+	"github.com/dean2032/go-project-layout/utils/tracing"
+	driver "github.com/go-sql-driver/mysql"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/fx"
 	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -20,11 +29,22 @@ import (
 // Database modal
 type Database struct {
 	*gorm.DB
+	// primary connection pool
+	primary *sql.DB
+	// replicas connection pools registered to dbresolver
+	replicas []*sql.DB
 }
 
-// NewDatabase creates a new database instance
-func NewDatabase(cfg *config.Config) *Database {
-	db, err := connectTo(cfg.MainDB, NewGormConfig(cfg), cfg.DBConnectionPoolSize)
+// NewDatabase creates a new database instance, its queries and pool stats are recorded by m,
+// queries of contexts with span are traced by tp, primary and replicas are pinged by checks of registry
+func NewDatabase(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	m *metrics.Metrics,
+	tp *sdktrace.TracerProvider,
+	registry *health.Registry,
+) *Database {
+	db, replicas, err := connectTo(cfg.MainDB, NewGormConfig(cfg), cfg.DBConnectionPoolSize)
 
 	if err != nil {
 		logging.Infof("Url: ", cfg.MainDB)
@@ -33,9 +53,57 @@ func NewDatabase(cfg *config.Config) *Database {
 
 	logging.Info("Database connection established")
 
-	return &Database{
-		DB: db,
+	primary, err := db.DB()
+	if err != nil {
+		logging.Panic(err.Error())
+	}
+	if err := db.Use(m.GormPlugin()); err != nil {
+		logging.Panic(err.Error())
+	}
+	if err := db.Use(tracing.NewGormPlugin(tp)); err != nil {
+		logging.Panic(err.Error())
 	}
+	m.RegisterDB(primary, replicas)
+	registry.Register("db.primary", primary.PingContext)
+	for i, replica := range replicas {
+		registry.Register(fmt.Sprintf("db.replica_%d", i), replica.PingContext)
+	}
+	database := &Database{
+		DB:       db,
+		primary:  primary,
+		replicas: replicas,
+	}
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return database.Close()
+		},
+	})
+	return database
+}
+
+// Primary returns connection pool of primary database
+func (d *Database) Primary() *sql.DB {
+	return d.primary
+}
+
+// Replicas returns connection pools of read replicas
+func (d *Database) Replicas() []*sql.DB {
+	return d.replicas
+}
+
+// Close closes connection pools of primary and replicas
+func (d *Database) Close() error {
+	logging.Info("closing database connections")
+	var firstErr error
+	for _, replica := range d.replicas {
+		if err := replica.Close(); err != nil && firstErr == nil {
+			firstErr = errors.Wrap(err, "close replica")
+		}
+	}
+	if err := d.primary.Close(); err != nil {
+		return errors.Wrap(err, "close primary")
+	}
+	return firstErr
 }
 
 func setPoolParam(db *gorm.DB, connPoolSize int) {
@@ -49,7 +117,7 @@ func setPoolParam(db *gorm.DB, connPoolSize int) {
 	sqlDB.SetConnMaxIdleTime(5 * time.Minute)
 }
 
-func connectTo(dbUrls string, gormConfig gorm.Config, connPoolSize int) (*gorm.DB, error) {
+func connectTo(dbUrls string, gormConfig gorm.Config, connPoolSize int) (*gorm.DB, []*sql.DB, error) {
 	if connPoolSize == 0 {
 		connPoolSize = 256
 	}
@@ -59,12 +127,19 @@ func connectTo(dbUrls string, gormConfig gorm.Config, connPoolSize int) (*gorm.D
 		SkipInitializeWithVersion: true,
 	}), &gormConfig)
 	if err != nil {
-		return nil, errors.Wrap(err, "mysql can't connect")
+		return nil, nil, errors.Wrap(err, "mysql can't connect")
 	}
 	replicas := make([]gorm.Dialector, 0, len(urls))
+	replicaPools := make([]*sql.DB, 0, len(urls))
 	for _, url := range urls[1:] {
 		if len(url) > 0 {
-			replicas = append(replicas, mysql.New(mysql.Config{DSN: url, SkipInitializeWithVersion: true}))
+			// open pools here to keep handles for closing
+			pool, err := sql.Open("mysql", url)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "mysql replica can't connect")
+			}
+			replicaPools = append(replicaPools, pool)
+			replicas = append(replicas, mysql.New(mysql.Config{Conn: pool, SkipInitializeWithVersion: true}))
 		}
 	}
 	if len(replicas) > 0 {
@@ -74,11 +149,11 @@ func connectTo(dbUrls string, gormConfig gorm.Config, connPoolSize int) (*gorm.D
 			SetMaxIdleConns(connPoolSize).
 			SetMaxOpenConns(connPoolSize))
 		if err != nil {
-			return nil, errors.WithStack(err)
+			return nil, nil, errors.WithStack(err)
 		}
 	}
 	setPoolParam(db, connPoolSize)
-	return db, nil
+	return db, replicaPools, nil
 }
 
 // NewGormConfig make gormConfig
@@ -104,3 +179,12 @@ func NewGormConfig(cfg *config.Config) gorm.Config {
 	}
 	return config
 }
+
+// mysqlErDupEntry error number of duplicate entry for unique key
+const mysqlErDupEntry = 1062
+
+// IsDuplicateKey returns whether err is caused by violation of unique key
+func IsDuplicateKey(err error) bool {
+	myErr, ok := errors.Cause(err).(*driver.MySQLError)
+	return ok && myErr.Number == mysqlErDupEntry
+}
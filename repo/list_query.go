@@ -0,0 +1,299 @@
+package repo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// list query parameter names
+const (
+	PageParam     = "page"
+	PageSizeParam = "page_size"
+	CursorParam   = "cursor"
+	SortParam     = "sort"
+)
+
+// default page size settings of ListSpec
+const (
+	defaultPageSize = 20
+	defaultMaxSize  = 100
+)
+
+// FilterOp operator of filter
+type FilterOp string
+
+// filter operators
+const (
+	OpEq       FilterOp = "="
+	OpGte      FilterOp = ">="
+	OpLte      FilterOp = "<="
+	OpContains FilterOp = "contains"
+)
+
+// FilterKind value kind of filter
+type FilterKind int
+
+// filter value kinds
+const (
+	FilterString FilterKind = iota
+	FilterInt
+)
+
+// FilterSpec defines a filter by query parameter
+type FilterSpec struct {
+	Column string
+	Op     FilterOp
+	Kind   FilterKind
+}
+
+// ListSpec whitelists sort fields and filters of a resource listing
+type ListSpec struct {
+	// SortFields maps sort parameter names to columns, columns should not be null for cursor pagination
+	SortFields map[string]string
+	// DefaultSort is used if sort not specified, e.g. "-created_at"
+	DefaultSort string
+	// KeyColumn unique column appended to order as tie breaker, "id" if empty
+	KeyColumn string
+	// Filters maps query parameter names to filters
+	Filters map[string]FilterSpec
+	// DefaultPageSize 20 if 0
+	DefaultPageSize int
+	// MaxPageSize 100 if 0
+	MaxPageSize int
+}
+
+// Sort order of a column
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// Filter condition of a column
+type Filter struct {
+	Column string
+	Op     FilterOp
+	Value  interface{}
+}
+
+// ListQuery pagination, filters and sorting of a listing.
+// Cursor pagination is used if Cursor is set, otherwise offset pagination by Page.
+type ListQuery struct {
+	Page     int
+	PageSize int
+	Cursor   string
+	Sorts    []Sort
+	Filters  []Filter
+}
+
+// Pagination result of a listing
+type Pagination struct {
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"page_size"`
+	Total      int64  `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ParseListQuery parses query parameters by spec:
+//
+//	page=2&page_size=10            offset pagination, page starts from 1
+//	cursor=<next_cursor>           cursor pagination, next_cursor is returned by previous page
+//	sort=-age,name                 sort by age desc then name asc, fields must be whitelisted
+//	<filter>=<value>               filters defined by spec, e.g. name=foo&age_min=18
+func ParseListQuery(values url.Values, spec ListSpec) (ListQuery, error) {
+	q := ListQuery{Page: 1, PageSize: spec.DefaultPageSize, Cursor: values.Get(CursorParam)}
+	if q.PageSize <= 0 {
+		q.PageSize = defaultPageSize
+	}
+	maxSize := spec.MaxPageSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxSize
+	}
+
+	var err error
+	if v := values.Get(PageParam); v != "" {
+		if q.Page, err = strconv.Atoi(v); err != nil || q.Page < 1 {
+			return q, errors.CodeErrorf(errors.InputError, "invalid %s: %s", PageParam, v)
+		}
+	}
+	if v := values.Get(PageSizeParam); v != "" {
+		if q.PageSize, err = strconv.Atoi(v); err != nil || q.PageSize < 1 || q.PageSize > maxSize {
+			return q, errors.CodeErrorf(errors.InputError, "invalid %s: %s, should be 1-%d", PageSizeParam, v, maxSize)
+		}
+	}
+
+	sort := values.Get(SortParam)
+	if sort == "" {
+		sort = spec.DefaultSort
+	}
+	for _, name := range strings.Split(sort, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		desc := strings.HasPrefix(name, "-")
+		column, ok := spec.SortFields[strings.TrimPrefix(name, "-")]
+		if !ok {
+			return q, errors.CodeErrorf(errors.InputError, "unsupported sort field: %s", strings.TrimPrefix(name, "-"))
+		}
+		q.Sorts = append(q.Sorts, Sort{Column: column, Desc: desc})
+	}
+	keyColumn := spec.KeyColumn
+	if keyColumn == "" {
+		keyColumn = "id"
+	}
+	if len(q.Sorts) == 0 || q.Sorts[len(q.Sorts)-1].Column != keyColumn {
+		// unique tie breaker makes order stable, which cursor pagination relies on
+		q.Sorts = append(q.Sorts, Sort{Column: keyColumn})
+	}
+
+	for name, filter := range spec.Filters {
+		v := values.Get(name)
+		if v == "" {
+			continue
+		}
+		var value interface{} = v
+		if filter.Kind == FilterInt {
+			if value, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return q, errors.CodeErrorf(errors.InputError, "invalid %s: %s", name, v)
+			}
+		}
+		q.Filters = append(q.Filters, Filter{Column: filter.Column, Op: filter.Op, Value: value})
+	}
+	return q, nil
+}
+
+// applyFilters adds where conditions of filters
+func (q ListQuery) applyFilters(db *gorm.DB) *gorm.DB {
+	for _, f := range q.Filters {
+		column := clause.Column{Name: f.Column}
+		switch f.Op {
+		case OpContains:
+			db = db.Where(clause.Like{Column: column, Value: "%" + escapeLike(fmt.Sprint(f.Value)) + "%"})
+		case OpGte:
+			db = db.Where(clause.Gte{Column: column, Value: f.Value})
+		case OpLte:
+			db = db.Where(clause.Lte{Column: column, Value: f.Value})
+		default:
+			db = db.Where(clause.Eq{Column: column, Value: f.Value})
+		}
+	}
+	return db
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// applyCursor adds keyset condition after the row of cursor:
+// (c1 > v1) OR (c1 = v1 AND c2 > v2) OR ..., with < for desc columns
+func (q ListQuery) applyCursor(db *gorm.DB, fields []*schema.Field) (*gorm.DB, error) {
+	invalid := errors.CodeErrorf(errors.InputError, "invalid cursor, sort should not change between pages")
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil || len(raws) != len(q.Sorts) {
+		return nil, invalid
+	}
+	// decode by field types, so that times and big integers are compared as they are
+	values := make([]interface{}, len(raws))
+	for i, raw := range raws {
+		value := reflect.New(fields[i].FieldType)
+		if err := json.Unmarshal(raw, value.Interface()); err != nil {
+			return nil, invalid
+		}
+		values[i] = value.Elem().Interface()
+	}
+
+	ors := make([]clause.Expression, 0, len(q.Sorts))
+	for i, sort := range q.Sorts {
+		ands := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, clause.Eq{Column: clause.Column{Name: q.Sorts[j].Column}, Value: values[j]})
+		}
+		column := clause.Column{Name: sort.Column}
+		if sort.Desc {
+			ands = append(ands, clause.Lt{Column: column, Value: values[i]})
+		} else {
+			ands = append(ands, clause.Gt{Column: column, Value: values[i]})
+		}
+		ors = append(ors, clause.And(ands...))
+	}
+	return db.Where(clause.Or(ors...)), nil
+}
+
+// sortFields looks up model fields of sort columns
+func (q ListQuery) sortFields(db *gorm.DB, dest interface{}) ([]*schema.Field, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(dest); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	fields := make([]*schema.Field, 0, len(q.Sorts))
+	for _, sort := range q.Sorts {
+		field := stmt.Schema.LookUpField(sort.Column)
+		if field == nil {
+			return nil, errors.Errorf("sort column %s not found in model %s", sort.Column, stmt.Schema.Name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// List finds a page of model matching q into dest, which is a pointer to slice of model
+func List(db *gorm.DB, q ListQuery, dest interface{}) (Pagination, error) {
+	p := Pagination{PageSize: q.PageSize}
+	fields, err := q.sortFields(db, dest)
+	if err != nil {
+		return p, err
+	}
+	query := q.applyFilters(db.Model(dest))
+	if err := query.Session(&gorm.Session{}).Count(&p.Total).Error; err != nil {
+		return p, err
+	}
+
+	query = query.Session(&gorm.Session{})
+	if q.Cursor != "" {
+		if query, err = q.applyCursor(query, fields); err != nil {
+			return p, err
+		}
+	} else {
+		p.Page = q.Page
+		query = query.Offset((q.Page - 1) * q.PageSize)
+	}
+	for _, sort := range q.Sorts {
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: sort.Column}, Desc: sort.Desc})
+	}
+	// fetch one more row to know whether next page exists
+	if err := query.Limit(q.PageSize + 1).Find(dest).Error; err != nil {
+		return p, err
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	if rows.Len() <= q.PageSize {
+		return p, nil
+	}
+	rows.Set(rows.Slice(0, q.PageSize))
+	last := reflect.Indirect(rows.Index(q.PageSize - 1))
+	values := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		value, _ := field.ValueOf(context.Background(), last)
+		values = append(values, value)
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return p, errors.WithStack(err)
+	}
+	p.NextCursor = base64.RawURLEncoding.EncodeToString(data)
+	return p, nil
+}
@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dean2032/go-project-layout/models"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+var testListSpec = ListSpec{
+	SortFields:  map[string]string{"id": "id", "name": "name", "age": "age"},
+	DefaultSort: "-age",
+	Filters: map[string]FilterSpec{
+		"name":    {Column: "name", Op: OpContains},
+		"age_min": {Column: "age", Op: OpGte, Kind: FilterInt},
+	},
+	MaxPageSize: 50,
+}
+
+func TestParseListQuery(t *testing.T) {
+	q, err := ParseListQuery(url.Values{}, testListSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Page != 1 || q.PageSize != defaultPageSize {
+		t.Error("unexpected default page", q.Page, q.PageSize)
+	}
+	if len(q.Sorts) != 2 || q.Sorts[0] != (Sort{Column: "age", Desc: true}) || q.Sorts[1] != (Sort{Column: "id"}) {
+		t.Error("default sort with tie breaker expected", q.Sorts)
+	}
+
+	values, _ := url.ParseQuery("page=3&page_size=10&sort=name,-id&name=bo&age_min=18&unknown=1")
+	if q, err = ParseListQuery(values, testListSpec); err != nil {
+		t.Fatal(err)
+	}
+	if q.Page != 3 || q.PageSize != 10 {
+		t.Error("unexpected page", q.Page, q.PageSize)
+	}
+	if len(q.Sorts) != 2 || q.Sorts[1] != (Sort{Column: "id", Desc: true}) {
+		t.Error("tie breaker should not be duplicated", q.Sorts)
+	}
+	if len(q.Filters) != 2 {
+		t.Error("unexpected filters", q.Filters)
+	}
+	for _, f := range q.Filters {
+		if f.Column == "age" && f.Value != int64(18) {
+			t.Errorf("int filter value expected, got %#v", f.Value)
+		}
+	}
+
+	for _, invalid := range []string{"page=0", "page=x", "page_size=51", "sort=email", "age_min=old"} {
+		values, _ := url.ParseQuery(invalid)
+		if _, err := ParseListQuery(values, testListSpec); !errors.IsCodeErrorEqual(err, errors.InputError) {
+			t.Error(invalid, "should fail with input error", err)
+		}
+	}
+}
+
+func dryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:3306)/test",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestListQuerySQL(t *testing.T) {
+	db := dryRunDB(t)
+	values, _ := url.ParseQuery("page=2&page_size=10&sort=-age&name=a_b&age_min=18")
+	q, err := ParseListQuery(values, testListSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := q.sortFields(db, &[]models.User{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var users []models.User
+		return q.applyFilters(tx.Model(&users)).Find(&users)
+	})
+	if !strings.Contains(sql, "`name` LIKE '%a\\_b%'") || !strings.Contains(sql, "`age` >= 18") {
+		t.Error("unexpected filter sql", sql)
+	}
+
+	q.Cursor = base64.RawURLEncoding.EncodeToString([]byte(`[30, 7]`))
+	sql = db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var users []models.User
+		tx, err := q.applyCursor(tx.Model(&users), fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx.Find(&users)
+	})
+	if !strings.Contains(sql, "(`age` < 30 OR (`age` = 30 AND `id` > 7))") {
+		t.Error("unexpected cursor sql", sql)
+	}
+
+	for _, cursor := range []string{"!", base64.RawURLEncoding.EncodeToString([]byte(`[30]`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`["old", 7]`))} {
+		q.Cursor = cursor
+		if _, err := q.applyCursor(db, fields); !errors.IsCodeErrorEqual(err, errors.InputError) {
+			t.Error(cursor, "should fail with input error", err)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package repo
+
+import (
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"github.com/dean2032/go-project-layout/utils/ratelimit"
+)
+
+// rateLimitKeyPrefix prefix of keys stored in redis by rate limiter
+const rateLimitKeyPrefix = "ratelimit:"
+
+// NewRateLimiter creates rate limiter backend of cfg.RateLimit.Backend, nil if rate limiting is disabled
+func NewRateLimiter(cfg *config.Config, redis *Redis) ratelimit.Limiter {
+	switch cfg.RateLimit.Backend {
+	case config.RateLimitBackendRedis:
+		logging.Info("using redis rate limiter")
+		return ratelimit.NewRedisLimiter(redis.Client, rateLimitKeyPrefix)
+	case config.RateLimitBackendMemory:
+		logging.Info("using memory rate limiter")
+		return ratelimit.NewMemoryLimiter()
+	default:
+		logging.Info("rate limiting disabled")
+		return nil
+	}
+}
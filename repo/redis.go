@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/health"
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/fx"
+)
+
+// redisPingTimeout timeout of ping on start
+const redisPingTimeout = 3 * time.Second
+
+// Redis redis client, Client is nil if redis address not configured
+type Redis struct {
+	*redis.Client
+}
+
+// NewRedis creates redis client of cfg.Redis, it is pinged on start and by check of registry, closed on stop
+func NewRedis(lc fx.Lifecycle, cfg *config.Config, registry *health.Registry) *Redis {
+	if cfg.Redis.Address == "" {
+		logging.Info("redis address not configured, redis disabled")
+		return &Redis{}
+	}
+	redis.SetLogger(logging.RedisLogger{})
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Address,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+		PoolSize: cfg.Redis.PoolSize,
+	})
+	client.AddHook(logging.NewRedisHook(100 * time.Millisecond))
+	r := &Redis{Client: client}
+	registry.Register("redis", r.Ping)
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			if err := r.Ping(ctx); err != nil {
+				return err
+			}
+			logging.Infof("redis connection established: %s", cfg.Redis.Address)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logging.Info("closing redis connections")
+			return errors.Wrap(client.Close(), "close redis")
+		},
+	})
+	return r
+}
+
+// Enabled returns whether redis is configured
+func (r *Redis) Enabled() bool {
+	return r.Client != nil
+}
+
+// Ping checks connection of redis
+func (r *Redis) Ping(ctx context.Context) error {
+	if !r.Enabled() {
+		return errors.New("redis not configured")
+	}
+	ctx, cancel := context.WithTimeout(ctx, redisPingTimeout)
+	defer cancel()
+	return errors.Wrapf(r.Client.Ping(ctx).Err(), "ping redis %s", r.Options().Addr)
+}
@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/health"
+	"go.uber.org/fx/fxtest"
+)
+
+func TestRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cfg := config.DefaultConfig()
+	cfg.Redis.Address = mr.Addr()
+
+	lc := fxtest.NewLifecycle(t)
+	registry := health.NewRegistry(cfg)
+	r := NewRedis(lc, cfg, registry)
+	lc.RequireStart()
+
+	ctx := context.Background()
+	if err := r.Set(ctx, "k", "v", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := mr.Get("k"); v != "v" {
+		t.Error("unexpected value", v)
+	}
+	if err := r.Ping(ctx); err != nil {
+		t.Error(err)
+	}
+	if report := registry.Check(ctx); report.Checks["redis"].Status != health.StatusUp {
+		t.Error("redis should be ready", report)
+	}
+
+	lc.RequireStop()
+	if err := r.Ping(ctx); err == nil {
+		t.Error("ping should fail after stop")
+	}
+}
+
+func TestRedisStartFail(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cfg := config.DefaultConfig()
+	cfg.Redis.Address = mr.Addr()
+	mr.Close()
+
+	lc := fxtest.NewLifecycle(t)
+	NewRedis(lc, cfg, health.NewRegistry(cfg))
+	if err := lc.Start(context.Background()); err == nil {
+		t.Error("start should fail when redis is unreachable")
+	}
+}
+
+func TestRedisDisabled(t *testing.T) {
+	cfg := config.DefaultConfig()
+	r := NewRedis(fxtest.NewLifecycle(t), cfg, health.NewRegistry(cfg))
+	if r.Enabled() {
+		t.Error("redis should be disabled without address")
+	}
+	if err := r.Ping(context.Background()); err == nil {
+		t.Error("ping should fail when disabled")
+	}
+}
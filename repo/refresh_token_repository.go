@@ -0,0 +1,29 @@
+package repo
+
+import (
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"gorm.io/gorm"
+)
+
+// RefreshTokenRepository database of refresh tokens
+type RefreshTokenRepository struct {
+	*Database
+}
+
+// NewRefreshTokenRepository creates a new refresh token repository
+func NewRefreshTokenRepository(db *Database) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		Database: db,
+	}
+}
+
+// WithTx returns a copy of repository with transaction
+func (r *RefreshTokenRepository) WithTx(txHandle *gorm.DB) *RefreshTokenRepository {
+	if txHandle == nil {
+		logging.Error("Transaction Database not found in gin context. ")
+		return r
+	}
+	db := *r.Database
+	db.DB = txHandle
+	return &RefreshTokenRepository{Database: &db}
+}
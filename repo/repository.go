@@ -5,5 +5,13 @@ import "go.uber.org/fx"
 // Module exports dependency
 var Module = fx.Options(
 	fx.Provide(NewUserRepository),
+	fx.Provide(NewRefreshTokenRepository),
+	fx.Provide(NewRoleRepository),
+	fx.Provide(NewAPIKeyRepository),
+	fx.Provide(NewUserIdentityRepository),
 	fx.Provide(NewDatabase),
+	fx.Provide(NewRedis),
+	fx.Provide(NewCache),
+	fx.Provide(NewRateLimiter),
+	fx.Provide(NewRevocationStore),
 )
@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"github.com/go-redis/redis/v8"
+)
+
+// revocationKeyPrefix prefix of keys stored in redis by revocation store
+const revocationKeyPrefix = "revoked:"
+
+// revocationSweepInterval min interval of removing expired entries of memory store
+const revocationSweepInterval = time.Minute
+
+// RevocationStore stores ids of revoked tokens until the tokens expire
+type RevocationStore interface {
+	// Revoke revokes token of id, the entry is removed after expiry of token
+	Revoke(ctx context.Context, id string, expiresAt time.Time) error
+	// IsRevoked returns whether token of id is revoked
+	IsRevoked(ctx context.Context, id string) (bool, error)
+}
+
+// NewRevocationStore creates revocation store of cfg.JWT.RevocationStore
+func NewRevocationStore(cfg *config.Config, redis *Redis) RevocationStore {
+	if cfg.JWT.RevocationStore == config.RevocationStoreRedis {
+		logging.Info("using redis revocation store")
+		return NewRedisRevocationStore(redis.Client)
+	}
+	logging.Info("using memory revocation store")
+	return NewMemoryRevocationStore()
+}
+
+// MemoryRevocationStore revocation store in memory, entries are never evicted before expiry
+type MemoryRevocationStore struct {
+	mu        sync.Mutex
+	revoked   map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryRevocationStore creates revocation store in memory
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		revoked:   map[string]time.Time{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Revoke ...
+func (s *MemoryRevocationStore) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) > revocationSweepInterval {
+		for k, exp := range s.revoked {
+			if now.After(exp) {
+				delete(s.revoked, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	if expiresAt.After(now) {
+		s.revoked[id] = expiresAt
+	}
+	return nil
+}
+
+// IsRevoked ...
+func (s *MemoryRevocationStore) IsRevoked(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exp, ok := s.revoked[id]
+	return ok && time.Now().Before(exp), nil
+}
+
+// Len returns number of entries including expired ones not swept
+func (s *MemoryRevocationStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.revoked)
+}
+
+// RedisRevocationStore revocation store in redis, entries expire with tokens
+type RedisRevocationStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisRevocationStore creates revocation store in redis
+func NewRedisRevocationStore(client redis.UniversalClient) *RedisRevocationStore {
+	return &RedisRevocationStore{client: client}
+}
+
+// Revoke ...
+func (s *RedisRevocationStore) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return errors.Wrap(s.client.Set(ctx, revocationKeyPrefix+id, 1, ttl).Err(), "revoke token")
+}
+
+// IsRevoked ...
+func (s *RedisRevocationStore) IsRevoked(ctx context.Context, id string) (bool, error) {
+	n, err := s.client.Exists(ctx, revocationKeyPrefix+id).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "check revoked token")
+	}
+	return n > 0, nil
+}
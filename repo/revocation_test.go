@@ -0,0 +1,53 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func testRevocationStore(t *testing.T, s RevocationStore, expire func(time.Duration)) {
+	ctx := context.Background()
+	if err := s.Revoke(ctx, "a", time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	// already expired tokens are not stored
+	if err := s.Revoke(ctx, "b", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]bool{"a": true, "b": false, "c": false} {
+		if revoked, err := s.IsRevoked(ctx, id); err != nil || revoked != want {
+			t.Error(id, revoked, err)
+		}
+	}
+	expire(2 * time.Minute)
+	if revoked, _ := s.IsRevoked(ctx, "a"); revoked {
+		t.Error("entry should expire with token")
+	}
+}
+
+func TestMemoryRevocationStore(t *testing.T) {
+	s := NewMemoryRevocationStore()
+	testRevocationStore(t, s, func(d time.Duration) {
+		// move entries and last sweep back instead of waiting
+		s.mu.Lock()
+		for k, exp := range s.revoked {
+			s.revoked[k] = exp.Add(-d)
+		}
+		s.lastSweep = s.lastSweep.Add(-d)
+		s.mu.Unlock()
+	})
+	_ = s.Revoke(context.Background(), "d", time.Now().Add(time.Minute))
+	if s.Len() != 1 {
+		t.Error("expired entries should be swept", s.Len())
+	}
+}
+
+func TestRedisRevocationStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	s := NewRedisRevocationStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	testRevocationStore(t, s, mr.FastForward)
+}
@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"github.com/dean2032/go-project-layout/models"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RoleRepository database of roles and their permissions
+type RoleRepository struct {
+	*Database
+}
+
+// NewRoleRepository creates a new role repository
+func NewRoleRepository(db *Database) *RoleRepository {
+	return &RoleRepository{
+		Database: db,
+	}
+}
+
+// WithTx returns a copy of repository with transaction
+func (r *RoleRepository) WithTx(txHandle *gorm.DB) *RoleRepository {
+	if txHandle == nil {
+		logging.Error("Transaction Database not found in gin context. ")
+		return r
+	}
+	db := *r.Database
+	db.DB = txHandle
+	return &RoleRepository{Database: &db}
+}
+
+// UserGrants returns names of roles of user and permissions of these roles
+func (r *RoleRepository) UserGrants(userID uint) (roles []string, permissions []string, err error) {
+	err = r.Model(&models.Role{}).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name").
+		Pluck("roles.name", &roles).Error
+	if err != nil {
+		return nil, nil, errors.CodeWrap(errors.DBError, err, "find roles of user")
+	}
+	err = r.Model(&models.RolePermission{}).
+		Distinct("role_permissions.permission").
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Order("role_permissions.permission").
+		Pluck("role_permissions.permission", &permissions).Error
+	if err != nil {
+		return nil, nil, errors.CodeWrap(errors.DBError, err, "find permissions of user")
+	}
+	return roles, permissions, nil
+}
+
+// AssignRole assigns role of name to user, assigning a role twice is ignored
+func (r *RoleRepository) AssignRole(userID uint, roleName string) error {
+	var role models.Role
+	if err := r.Where("name = ?", roleName).Limit(1).Find(&role).Error; err != nil {
+		return errors.CodeWrap(errors.DBError, err, "find role")
+	}
+	if role.ID == 0 {
+		return errors.CodeErrorf(errors.NotFound, "role %s not found", roleName)
+	}
+	err := r.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.UserRole{UserID: userID, RoleID: role.ID}).Error
+	return errors.CodeWrap(errors.DBError, err, "assign role")
+}
@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"sync"
+
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"gorm.io/gorm"
+)
+
+// afterCommits callbacks registered by AfterCommit, keyed by connection of transaction
+var afterCommits = struct {
+	sync.Mutex
+	callbacks map[gorm.ConnPool][]func()
+}{callbacks: map[gorm.ConnPool][]func(){}}
+
+// InTx returns whether db is a transaction handle
+func InTx(db *gorm.DB) bool {
+	_, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
+
+// AfterCommit registers fn to be called after tx is committed by CommitTx,
+// fn is discarded if tx is rolled back by RollbackTx. fn is called at once if tx is not a transaction.
+func AfterCommit(tx *gorm.DB, fn func()) {
+	if !InTx(tx) {
+		fn()
+		return
+	}
+	afterCommits.Lock()
+	defer afterCommits.Unlock()
+	pool := tx.Statement.ConnPool
+	afterCommits.callbacks[pool] = append(afterCommits.callbacks[pool], fn)
+}
+
+func popAfterCommits(tx *gorm.DB) []func() {
+	afterCommits.Lock()
+	defer afterCommits.Unlock()
+	pool := tx.Statement.ConnPool
+	callbacks := afterCommits.callbacks[pool]
+	delete(afterCommits.callbacks, pool)
+	return callbacks
+}
+
+// CommitTx commits tx and calls callbacks registered by AfterCommit
+func CommitTx(tx *gorm.DB) error {
+	callbacks := popAfterCommits(tx)
+	if err := tx.Commit().Error; err != nil {
+		return errors.Wrap(err, "commit transaction")
+	}
+	for _, fn := range callbacks {
+		fn()
+	}
+	return nil
+}
+
+// RollbackTx rollbacks tx and discards callbacks registered by AfterCommit
+func RollbackTx(tx *gorm.DB) error {
+	popAfterCommits(tx)
+	return errors.Wrap(tx.Rollback().Error, "rollback transaction")
+}
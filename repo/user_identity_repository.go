@@ -0,0 +1,29 @@
+package repo
+
+import (
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"gorm.io/gorm"
+)
+
+// UserIdentityRepository database of external identities of users
+type UserIdentityRepository struct {
+	*Database
+}
+
+// NewUserIdentityRepository creates a new user identity repository
+func NewUserIdentityRepository(db *Database) *UserIdentityRepository {
+	return &UserIdentityRepository{
+		Database: db,
+	}
+}
+
+// WithTx returns a copy of repository with transaction
+func (r *UserIdentityRepository) WithTx(txHandle *gorm.DB) *UserIdentityRepository {
+	if txHandle == nil {
+		logging.Error("Transaction Database not found in gin context. ")
+		return r
+	}
+	db := *r.Database
+	db.DB = txHandle
+	return &UserIdentityRepository{Database: &db}
+}
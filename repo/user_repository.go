@@ -17,12 +17,13 @@ func NewUserRepository(db *Database) *UserRepository {
 	}
 }
 
-// WithTx enables repository with transaction
+// WithTx returns a copy of repository with transaction
 func (r *UserRepository) WithTx(txHandle *gorm.DB) *UserRepository {
 	if txHandle == nil {
 		logging.Error("Transaction Database not found in gin context. ")
 		return r
 	}
-	r.Database.DB = txHandle
-	return r
+	db := *r.Database
+	db.DB = txHandle
+	return &UserRepository{Database: &db}
 }
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/dean2032/go-project-layout/models"
+	"github.com/dean2032/go-project-layout/repo"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"go.uber.org/zap"
+)
+
+const (
+	// APIKeyHeader header of api key
+	APIKeyHeader = "X-API-Key"
+	// apiKeyPrefix marks api keys, so that leaked keys are easy to find
+	apiKeyPrefix = "ak_"
+	// apiKeyPrefixLen length of key prefix stored to identify keys
+	apiKeyPrefixLen = 10
+	// lastUsedInterval last_used_at is updated at most once per interval to save writes
+	lastUsedInterval = time.Minute
+)
+
+// APIKeyService service of api keys for service to service calls
+type APIKeyService struct {
+	keys *repo.APIKeyRepository
+}
+
+// NewAPIKeyService creates a new api key service
+func NewAPIKeyService(keys *repo.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		keys: keys,
+	}
+}
+
+// Create creates api key of scopes, it never expires if ttl is 0.
+// The returned key is shown only once, only its hash is stored.
+func (s *APIKeyService) Create(name, owner string, scopes []string, ttl time.Duration) (string, models.APIKey, error) {
+	key, err := generateAPIKey()
+	if err != nil {
+		return "", models.APIKey{}, err
+	}
+	apiKey := models.APIKey{
+		Name:    name,
+		Owner:   owner,
+		Prefix:  key[:apiKeyPrefixLen],
+		KeyHash: hashToken(key),
+		Scopes:  strings.Join(scopes, " "),
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		apiKey.ExpiresAt = &expiresAt
+	}
+	if err := s.keys.Create(&apiKey).Error; err != nil {
+		return "", apiKey, errors.CodeWrap(errors.DBError, err, "create api key")
+	}
+	return key, apiKey, nil
+}
+
+// List returns all api keys including revoked and expired ones
+func (s *APIKeyService) List() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := s.keys.Order("id").Find(&keys).Error
+	return keys, errors.CodeWrap(errors.DBError, err, "list api keys")
+}
+
+// Revoke revokes api key of id, revoking a revoked key is no-op
+func (s *APIKeyService) Revoke(id uint) error {
+	var key models.APIKey
+	if err := s.keys.Where("id = ?", id).Limit(1).Find(&key).Error; err != nil {
+		return errors.CodeWrap(errors.DBError, err, "find api key")
+	}
+	if key.ID == 0 {
+		return errors.CodeErrorf(errors.NotFound, "api key %d not found", id)
+	}
+	err := s.keys.Model(&key).Where("revoked_at IS NULL").Update("revoked_at", time.Now()).Error
+	return errors.CodeWrap(errors.DBError, err, "revoke api key")
+}
+
+// Authenticate returns principal of api key with its scopes, AuthError is returned if key is invalid
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*Principal, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, errors.CodeErrorf(errors.AuthError, "invalid api key")
+	}
+	var apiKey models.APIKey
+	if err := s.keys.WithContext(ctx).Where("key_hash = ?", hashToken(key)).Limit(1).Find(&apiKey).Error; err != nil {
+		return nil, errors.CodeWrap(errors.DBError, err, "find api key")
+	}
+	now := time.Now()
+	if err := checkAPIKey(apiKey, now); err != nil {
+		return nil, err
+	}
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= lastUsedInterval {
+		if err := s.keys.WithContext(ctx).Model(&apiKey).UpdateColumn("last_used_at", now).Error; err != nil {
+			logging.Error("update last used time of api key fail", zap.Uint("api_key_id", apiKey.ID), zap.Error(err))
+		}
+	}
+	return &Principal{
+		APIKeyID: apiKey.ID,
+		Scopes:   strings.Fields(apiKey.Scopes),
+	}, nil
+}
+
+// checkAPIKey checks api key found by hash is usable at now
+func checkAPIKey(key models.APIKey, now time.Time) error {
+	switch {
+	case key.ID == 0:
+		return errors.CodeErrorf(errors.AuthError, "invalid api key")
+	case key.RevokedAt != nil:
+		return errors.CodeErrorf(errors.AuthError, "api key revoked")
+	case key.ExpiresAt != nil && now.After(*key.ExpiresAt):
+		return errors.CodeErrorf(errors.AuthError, "api key expired")
+	}
+	return nil
+}
+
+// generateAPIKey returns apiKeyPrefix followed by 32 random bytes in base64
+func generateAPIKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", errors.Wrap(err, "generate api key")
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
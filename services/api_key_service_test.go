@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dean2032/go-project-layout/models"
+	"github.com/dean2032/go-project-layout/utils/errors"
+)
+
+func TestCheckAPIKey(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+	cases := map[string]struct {
+		key   models.APIKey
+		valid bool
+	}{
+		"not found":     {models.APIKey{}, false},
+		"valid":         {models.APIKey{ID: 1}, true},
+		"not expired":   {models.APIKey{ID: 1, ExpiresAt: &future}, true},
+		"expired":       {models.APIKey{ID: 1, ExpiresAt: &past}, false},
+		"revoked":       {models.APIKey{ID: 1, RevokedAt: &past}, false},
+		"revoked later": {models.APIKey{ID: 1, ExpiresAt: &future, RevokedAt: &past}, false},
+	}
+	for name, tc := range cases {
+		err := checkAPIKey(tc.key, now)
+		if tc.valid != (err == nil) || (err != nil && !errors.IsCodeErrorEqual(err, errors.AuthError)) {
+			t.Error(name, err)
+		}
+	}
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, err := generateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := generateAPIKey()
+	if !strings.HasPrefix(key, apiKeyPrefix) || len(key) != len(apiKeyPrefix)+43 || key == other {
+		t.Error("unexpected key", key)
+	}
+	// keys without prefix are rejected before looking up
+	s := NewAPIKeyService(nil)
+	if _, err := s.Authenticate(context.Background(), "token"); !errors.IsCodeErrorEqual(err, errors.AuthError) {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clientCertKey key of client certificate in context.Context
+type clientCertKey struct{}
+
+// ClientCert identity of the verified client certificate of mutual tls
+type ClientCert struct {
+	// CommonName common name of subject
+	CommonName     string
+	Subject        string
+	Issuer         string
+	SerialNumber   string
+	DNSNames       []string
+	EmailAddresses []string
+	URIs           []string
+	// Fingerprint hex sha256 of the certificate
+	Fingerprint string
+}
+
+// ClientCertOf returns identity of the verified client certificate of connection state,
+// ok is false if the client sent no certificate or it's not verified
+func ClientCertOf(state *tls.ConnectionState) (cc *ClientCert, ok bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	cert := state.VerifiedChains[0][0]
+	fingerprint := sha256.Sum256(cert.Raw)
+	cc = &ClientCert{
+		CommonName:     cert.Subject.CommonName,
+		Subject:        cert.Subject.String(),
+		Issuer:         cert.Issuer.String(),
+		SerialNumber:   cert.SerialNumber.String(),
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		Fingerprint:    hex.EncodeToString(fingerprint[:]),
+	}
+	for _, u := range cert.URIs {
+		cc.URIs = append(cc.URIs, u.String())
+	}
+	return cc, true
+}
+
+// WithClientCert returns a copy of ctx with client certificate
+func WithClientCert(ctx context.Context, cc *ClientCert) context.Context {
+	return context.WithValue(ctx, clientCertKey{}, cc)
+}
+
+// ClientCertFromContext returns client certificate of ctx, which is *gin.Context or context of request,
+// ok is false if the request is not of mutual tls
+func ClientCertFromContext(ctx context.Context) (cc *ClientCert, ok bool) {
+	if gc, isGin := ctx.(*gin.Context); isGin {
+		if gc.Request == nil {
+			return nil, false
+		}
+		ctx = gc.Request.Context()
+	}
+	cc, ok = ctx.Value(clientCertKey{}).(*ClientCert)
+	return cc, ok
+}
@@ -1,57 +1,306 @@
 package services
 
 import (
-	"errors"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dean2032/go-project-layout/config"
 	"github.com/dean2032/go-project-layout/models"
+	"github.com/dean2032/go-project-layout/repo"
+	"github.com/dean2032/go-project-layout/utils/errors"
 	"github.com/dean2032/go-project-layout/utils/logging"
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/rs/xid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
+// TokenTypeBearer token type of access token
+const TokenTypeBearer = "Bearer"
+
+// Claims of access token, subject is id of user
+type Claims struct {
+	UserID uint     `json:"id"`
+	Name   string   `json:"name,omitempty"`
+	Email  string   `json:"email,omitempty"`
+	Roles  []string `json:"roles,omitempty"`
+	// Scope space separated scopes
+	Scope string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// TokenPair tokens issued by sign in and refresh
+type TokenPair struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	// ExpiresIn seconds of access token lifetime
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
 // JWTAuthService service relating to authorization
 type JWTAuthService struct {
 	cfg *config.Config
+	// keys signs tokens asymmetrically if jwt.keys_dir configured, otherwise jwt_secret is used
+	keys        *jwtKeySet
+	tokens      *repo.RefreshTokenRepository
+	roles       *repo.RoleRepository
+	revocations repo.RevocationStore
+	userService *UserService
 }
 
 // NewJWTAuthService creates a new auth service
-func NewJWTAuthService(cfg *config.Config) *JWTAuthService {
-	return &JWTAuthService{
-		cfg: cfg,
-	}
-}
-
-// Authorize authorizes the generated token
-func (s *JWTAuthService) Authorize(tokenString string) (bool, error) {
-	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-		return []byte(s.cfg.JWTSecret), nil
-	})
-	if token.Valid {
-		return true, nil
-	} else if ve, ok := err.(*jwt.ValidationError); ok {
-		if ve.Errors&jwt.ValidationErrorMalformed != 0 {
-			return false, errors.New("token malformed")
+func NewJWTAuthService(
+	cfg *config.Config,
+	tokens *repo.RefreshTokenRepository,
+	roles *repo.RoleRepository,
+	revocations repo.RevocationStore,
+	userService *UserService,
+) (*JWTAuthService, error) {
+	s := &JWTAuthService{
+		cfg:         cfg,
+		tokens:      tokens,
+		roles:       roles,
+		revocations: revocations,
+		userService: userService,
+	}
+	if cfg.JWT.KeysDir != "" {
+		keys, err := loadJWTKeys(cfg.JWT.KeysDir, cfg.JWT.SigningKID)
+		if err != nil {
+			return nil, errors.CodeWrap(errors.ConfigError, err, "load jwt keys")
 		}
-		if ve.Errors&(jwt.ValidationErrorExpired|jwt.ValidationErrorNotValidYet) != 0 {
-			return false, errors.New("token expired")
+		s.keys = keys
+		logging.Infof("signing jwt by key %s of %d keys", keys.signing.id, len(keys.keys))
+	}
+	return s, nil
+}
+
+// WithTx returns a copy of service issuing tokens in transaction,
+// so that grants of users created in the transaction are seen
+func (s *JWTAuthService) WithTx(txHandle *gorm.DB) *JWTAuthService {
+	cp := *s
+	cp.tokens = s.tokens.WithTx(txHandle)
+	cp.roles = s.roles.WithTx(txHandle)
+	return &cp
+}
+
+// JWKS returns public keys to verify tokens, it's empty if tokens are signed by jwt_secret
+func (s *JWTAuthService) JWKS() JWKS {
+	if s.keys == nil {
+		return JWKS{Keys: []JWK{}}
+	}
+	return s.keys.jwks()
+}
+
+// keyFunc returns key to verify token and algorithms accepted
+func (s *JWTAuthService) keyFunc() (jwt.Keyfunc, []string) {
+	if s.keys == nil {
+		return func(t *jwt.Token) (interface{}, error) {
+			return []byte(s.cfg.JWTSecret), nil
+		}, []string{jwt.SigningMethodHS256.Alg()}
+	}
+	algs := make([]string, 0, len(s.keys.keys))
+	for _, key := range s.keys.keys {
+		algs = append(algs, key.method.Alg())
+	}
+	return s.keys.verifyKey, algs
+}
+
+// Authorize validates signature, expiry, issuer and audience of access token,
+// and checks it's not revoked by logout, returns its claims
+func (s *JWTAuthService) Authorize(ctx context.Context, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	keyFunc, algs := s.keyFunc()
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc, jwt.WithValidMethods(algs))
+	if err != nil || !token.Valid {
+		if ve, ok := err.(*jwt.ValidationError); ok {
+			if ve.Errors&jwt.ValidationErrorMalformed != 0 {
+				return nil, errors.CodeErrorf(errors.AuthError, "token malformed")
+			}
+			if ve.Errors&(jwt.ValidationErrorExpired|jwt.ValidationErrorNotValidYet) != 0 {
+				return nil, errors.CodeErrorf(errors.AuthError, "token expired")
+			}
 		}
+		return nil, errors.CodeWrap(errors.AuthError, err, "invalid token")
+	}
+	if claims.ExpiresAt == nil {
+		return nil, errors.CodeErrorf(errors.AuthError, "token without expiry")
+	}
+	if iss := s.cfg.JWT.Issuer; iss != "" && !claims.VerifyIssuer(iss, true) {
+		return nil, errors.CodeErrorf(errors.AuthError, "unexpected token issuer %s", claims.Issuer)
+	}
+	if aud := s.cfg.JWT.Audience; aud != "" && !claims.VerifyAudience(aud, true) {
+		return nil, errors.CodeErrorf(errors.AuthError, "unexpected token audience %s", claims.Audience)
+	}
+	revoked, err := s.revocations.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		// fail closed, the token may be revoked
+		return nil, errors.CodeWrap(errors.AuthError, err, "check token revocation")
+	}
+	if revoked {
+		return nil, errors.CodeErrorf(errors.AuthError, "token revoked")
+	}
+	return claims, nil
+}
+
+// Logout revokes access token of principal until it expires,
+// and refresh tokens of the sign in if refreshToken is not empty
+func (s *JWTAuthService) Logout(ctx context.Context, principal *Principal, refreshToken string) error {
+	if err := s.revocations.Revoke(ctx, principal.TokenID, principal.ExpiresAt); err != nil {
+		return err
+	}
+	if refreshToken == "" {
+		return nil
+	}
+	var token models.RefreshToken
+	if err := s.tokens.Where("token_hash = ?", hashToken(refreshToken)).Limit(1).Find(&token).Error; err != nil {
+		return errors.CodeWrap(errors.DBError, err, "find refresh token")
 	}
-	return false, errors.New("couldn't handle token")
+	// refresh token of other users is ignored as an invalid one
+	if token.ID == 0 || token.UserID != principal.UserID {
+		return errors.CodeErrorf(errors.AuthError, "invalid refresh token")
+	}
+	return s.revokeFamily(token.FamilyID)
 }
 
-// CreateToken creates jwt auth token
-func (s *JWTAuthService) CreateToken(user models.User) string {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":    user.ID,
-		"name":  user.Name,
-		"email": *user.Email,
-	})
+// CreateToken creates jwt access token of user granted roles and scopes
+func (s *JWTAuthService) CreateToken(user models.User, roles, scopes []string) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID: user.ID,
+		Name:   user.Name,
+		Roles:  roles,
+		Scope:  strings.Join(scopes, " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        xid.New().String(),
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Issuer:    s.cfg.JWT.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.cfg.JWT.AccessTTLDuration())),
+		},
+	}
+	if aud := s.cfg.JWT.Audience; aud != "" {
+		claims.Audience = jwt.ClaimStrings{aud}
+	}
+	if user.Email != nil {
+		claims.Email = *user.Email
+	}
+	if s.keys == nil {
+		tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.cfg.JWTSecret))
+		return tokenString, errors.Wrap(err, "sign token")
+	}
+	token := jwt.NewWithClaims(s.keys.signing.method, claims)
+	token.Header["kid"] = s.keys.signing.id
+	tokenString, err := token.SignedString(s.keys.signing.private)
+	return tokenString, errors.Wrap(err, "sign token")
+}
 
-	tokenString, err := token.SignedString([]byte(s.cfg.JWTSecret))
+// IssueTokens creates access token and refresh token of a new sign in
+func (s *JWTAuthService) IssueTokens(user models.User) (TokenPair, error) {
+	// expired tokens of the user are not useful any more
+	if err := s.tokens.Where("user_id = ? AND expires_at < ?", user.ID, time.Now()).
+		Delete(&models.RefreshToken{}).Error; err != nil {
+		logging.Error("delete expired refresh tokens fail", zap.Uint("user_id", user.ID), zap.Error(err))
+	}
+	return s.issueTokens(user, xid.New().String())
+}
 
+// issueTokens creates tokens with roles and permissions of user at the time,
+// so that changes of grants take effect on refresh
+func (s *JWTAuthService) issueTokens(user models.User, familyID string) (TokenPair, error) {
+	roles, permissions, err := s.roles.UserGrants(user.ID)
 	if err != nil {
-		logging.Errorf("JWT validation failed: %s", err.Error())
+		return TokenPair{}, err
+	}
+	accessToken, err := s.CreateToken(user, roles, permissions)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return TokenPair{}, errors.Wrap(err, "generate refresh token")
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(secret)
+	if err := s.tokens.Create(&models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.cfg.JWT.RefreshTTLDuration()),
+	}).Error; err != nil {
+		return TokenPair{}, errors.CodeWrap(errors.DBError, err, "save refresh token")
+	}
+	return TokenPair{
+		AccessToken:  accessToken,
+		TokenType:    TokenTypeBearer,
+		ExpiresIn:    s.cfg.JWT.AccessTTL,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// Refresh rotates refresh token: it's exchanged for new tokens and can't be used again.
+// Using a rotated token revokes all tokens of the sign in, since it may be stolen.
+func (s *JWTAuthService) Refresh(refreshToken string) (TokenPair, error) {
+	var token models.RefreshToken
+	if err := s.tokens.Where("token_hash = ?", hashToken(refreshToken)).Limit(1).Find(&token).Error; err != nil {
+		return TokenPair{}, errors.CodeWrap(errors.DBError, err, "find refresh token")
 	}
+	now := time.Now()
+	switch {
+	case token.ID == 0:
+		return TokenPair{}, errors.CodeErrorf(errors.AuthError, "invalid refresh token")
+	case token.RevokedAt != nil:
+		return TokenPair{}, errors.CodeErrorf(errors.AuthError, "refresh token revoked")
+	case token.UsedAt != nil:
+		return TokenPair{}, s.reused(token)
+	case now.After(token.ExpiresAt):
+		return TokenPair{}, errors.CodeErrorf(errors.AuthError, "refresh token expired")
+	}
+
+	// concurrent use of the same token is reuse as well, only one of them updates the row.
+	// a family revoked since the read is not rotated either
+	result := s.tokens.Model(&token).Where("used_at IS NULL AND revoked_at IS NULL").Update("used_at", now)
+	if result.Error != nil {
+		return TokenPair{}, errors.CodeWrap(errors.DBError, result.Error, "rotate refresh token")
+	}
+	if result.RowsAffected == 0 {
+		return TokenPair{}, s.reused(token)
+	}
+
+	user, err := s.userService.GetOneUser(token.UserID)
+	if errors.IsCodeErrorEqual(err, errors.NotFound) {
+		return TokenPair{}, errors.CodeErrorf(errors.AuthError, "user of refresh token not found")
+	}
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return s.issueTokens(user, token.FamilyID)
+}
+
+// reused revokes the family of reused token
+func (s *JWTAuthService) reused(token models.RefreshToken) error {
+	logging.Warn("refresh token reused, revoking tokens of the sign in",
+		zap.Uint("user_id", token.UserID), zap.String("family_id", token.FamilyID))
+	if err := s.revokeFamily(token.FamilyID); err != nil {
+		return err
+	}
+	return errors.CodeErrorf(errors.AuthError, "refresh token reused, please sign in again")
+}
+
+func (s *JWTAuthService) revokeFamily(familyID string) error {
+	err := s.tokens.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+	return errors.CodeWrap(errors.DBError, err, "revoke refresh tokens")
+}
 
-	return tokenString
+// hashToken returns hex of sha256 of token, tokens are random enough for a fast hash
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/models"
+	"github.com/dean2032/go-project-layout/repo"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func testAuthService(t *testing.T, keysDir string) *JWTAuthService {
+	cfg := config.DefaultConfig()
+	cfg.JWTSecret = "secret"
+	cfg.JWT.Issuer = "issuer"
+	cfg.JWT.Audience = "audience"
+	cfg.JWT.KeysDir = keysDir
+	s, err := NewJWTAuthService(cfg, nil, nil, repo.NewMemoryRevocationStore(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func signToken(t *testing.T, claims jwt.Claims, method jwt.SigningMethod, kid string, key interface{}) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func validClaims() *jwt.RegisteredClaims {
+	return &jwt.RegisteredClaims{
+		Issuer:    "issuer",
+		Audience:  jwt.ClaimStrings{"audience"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+	s := testAuthService(t, "")
+	// user without email should not panic
+	token, err := s.CreateToken(models.User{ID: 7, Name: "bob"}, []string{"admin"}, []string{"users:read", "users:write"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := s.Authorize(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != 7 || claims.Subject != "7" || claims.ID == "" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if p := claims.Principal(); !p.HasRole("admin") || !p.HasScope("users:write") || p.HasScope("users:read users:write") {
+		t.Errorf("unexpected grants %+v", p)
+	}
+	if ttl := claims.ExpiresAt.Sub(claims.IssuedAt.Time); ttl != s.cfg.JWT.AccessTTLDuration() {
+		t.Error("unexpected ttl", ttl)
+	}
+
+	secret := []byte("secret")
+	expired, noExp, wrongIss, wrongAud := validClaims(), validClaims(), validClaims(), validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	noExp.ExpiresAt = nil
+	wrongIss.Issuer = "other"
+	wrongAud.Audience = jwt.ClaimStrings{"other"}
+	invalids := map[string]string{
+		"malformed": "not a token",
+		"expired":   signToken(t, expired, jwt.SigningMethodHS256, "", secret),
+		"no exp":    signToken(t, noExp, jwt.SigningMethodHS256, "", secret),
+		"wrong iss": signToken(t, wrongIss, jwt.SigningMethodHS256, "", secret),
+		"wrong aud": signToken(t, wrongAud, jwt.SigningMethodHS256, "", secret),
+		"wrong key": signToken(t, validClaims(), jwt.SigningMethodHS256, "", []byte("other")),
+		"HS512":     signToken(t, validClaims(), jwt.SigningMethodHS512, "", secret),
+		"alg none":  signToken(t, validClaims(), jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType),
+		"tampered":  token[:len(token)-2] + "xx",
+		"empty":     "",
+	}
+	if _, err := s.Authorize(ctx, signToken(t, validClaims(), jwt.SigningMethodHS256, "", secret)); err != nil {
+		t.Error("valid token should be authorized", err)
+	}
+	for name, token := range invalids {
+		if _, err := s.Authorize(ctx, token); !errors.IsCodeErrorEqual(err, errors.AuthError) {
+			t.Error(name, "should fail with auth error", err)
+		}
+	}
+
+	if err := s.Logout(ctx, claims.Principal(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authorize(ctx, token); !errors.IsCodeErrorEqual(err, errors.AuthError) {
+		t.Error("token should be revoked by logout", err)
+	}
+}
+
+func writeKey(t *testing.T, dir, kid, pemType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: pemType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+jwtKeyExt), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAsymmetricKeys(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	retired, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	writeKey(t, dir, "1-rsa", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	ecDER, _ := x509.MarshalECPrivateKey(ecKey)
+	writeKey(t, dir, "2-ec", "EC PRIVATE KEY", ecDER)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	writeKey(t, dir, "3-ed", "PRIVATE KEY", edDER)
+	retiredDER, _ := x509.MarshalPKIXPublicKey(retired.Public())
+	writeKey(t, dir, "0-retired", "PUBLIC KEY", retiredDER)
+
+	s := testAuthService(t, dir)
+	if s.keys.signing.id != "3-ed" {
+		t.Error("last private key should sign", s.keys.signing.id)
+	}
+	token, err := s.CreateToken(models.User{ID: 7}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authorize(ctx, token); err != nil {
+		t.Error(err)
+	}
+
+	keys := map[string]struct {
+		method jwt.SigningMethod
+		key    crypto.Signer
+	}{
+		"1-rsa":     {jwt.SigningMethodRS256, rsaKey},
+		"2-ec":      {jwt.SigningMethodES256, ecKey},
+		"3-ed":      {jwt.SigningMethodEdDSA, edKey},
+		"0-retired": {jwt.SigningMethodES384, retired},
+	}
+	for kid, k := range keys {
+		if _, err := s.Authorize(ctx, signToken(t, validClaims(), k.method, kid, k.key)); err != nil {
+			t.Error(kid, "should be verified", err)
+		}
+	}
+
+	rsaPublic, _ := x509.MarshalPKIXPublicKey(rsaKey.Public())
+	invalids := map[string]string{
+		// public key used as HMAC secret
+		"alg confusion": signToken(t, validClaims(), jwt.SigningMethodHS256, "1-rsa",
+			pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPublic})),
+		"alg of other key": signToken(t, validClaims(), jwt.SigningMethodRS256, "2-ec", rsaKey),
+		"unknown kid":      signToken(t, validClaims(), jwt.SigningMethodRS256, "9", rsaKey),
+		"no kid":           signToken(t, validClaims(), jwt.SigningMethodRS256, "", rsaKey),
+		"jwt_secret":       signToken(t, validClaims(), jwt.SigningMethodHS256, "", []byte("secret")),
+	}
+	for name, token := range invalids {
+		if _, err := s.Authorize(ctx, token); !errors.IsCodeErrorEqual(err, errors.AuthError) {
+			t.Error(name, "should fail with auth error", err)
+		}
+	}
+
+	jwks := s.JWKS()
+	if len(jwks.Keys) != 4 {
+		t.Fatal("unexpected jwks", jwks)
+	}
+	want := map[string]string{"0-retired": "EC/ES384/P-384", "1-rsa": "RSA/RS256/", "2-ec": "EC/ES256/P-256", "3-ed": "OKP/EdDSA/Ed25519"}
+	for _, k := range jwks.Keys {
+		if got := k.KeyType + "/" + k.Algorithm + "/" + k.Curve; got != want[k.KeyID] || k.Use != "sig" {
+			t.Error(k.KeyID, got)
+		}
+	}
+	if jwks.Keys[1].E != "AQAB" || len(jwks.Keys[2].X) != 43 {
+		t.Error("unexpected key parameters", jwks.Keys[1].E, jwks.Keys[2].X)
+	}
+
+	if _, err := loadJWTKeys(dir, "0-retired"); err == nil {
+		t.Error("public key should not be the signing key")
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// jwtKeyExt extension of key files in jwt.keys_dir, file name without it is the kid
+const jwtKeyExt = ".pem"
+
+// jwtKey key to sign or verify tokens, private is nil for keys only used to verify
+type jwtKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// jwtKeySet keys identified by kid
+type jwtKeySet struct {
+	keys    map[string]*jwtKey
+	signing *jwtKey
+}
+
+// loadJWTKeys loads keys of <kid>.pem files in dir, both private and public keys in PEM are supported.
+// signingKID is the key to sign tokens, the last private key by kid is used if empty.
+// Keys only to verify tokens are kept during rotation until tokens signed by them expire.
+func loadJWTKeys(dir, signingKID string) (*jwtKeySet, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+jwtKeyExt))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sort.Strings(files)
+	set := &jwtKeySet{keys: map[string]*jwtKey{}}
+	for _, file := range files {
+		key, err := loadJWTKey(file)
+		if err != nil {
+			return nil, err
+		}
+		set.keys[key.id] = key
+		if key.private != nil && (signingKID == "" || signingKID == key.id) {
+			set.signing = key
+		}
+	}
+	if set.signing == nil {
+		if signingKID != "" {
+			return nil, errors.Errorf("private key of jwt.signing_kid %s not found in %s", signingKID, dir)
+		}
+		return nil, errors.Errorf("no private key found in %s", dir)
+	}
+	return set, nil
+}
+
+func loadJWTKey(file string) (*jwtKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Errorf("%s: no PEM data found", file)
+	}
+	key := &jwtKey{id: strings.TrimSuffix(filepath.Base(file), jwtKeyExt)}
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, errors.Errorf("%s: unsupported PEM type %s", file, block.Type)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: parse key", file)
+	}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.private = signer
+		key.public = signer.Public()
+	} else {
+		key.public = parsed
+	}
+	if key.method, err = signingMethodOf(key.public); err != nil {
+		return nil, errors.Wrap(err, file)
+	}
+	return key, nil
+}
+
+// signingMethodOf returns signing method by type of key: RS256, ES256/ES384/ES512 by curve, or EdDSA
+func signingMethodOf(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, errors.Errorf("unsupported curve %s", k.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, errors.Errorf("unsupported key type %T", public)
+}
+
+// verifyKey returns public key of kid, alg of token must be the one of key to prevent algorithm confusion
+func (s *jwtKeySet) verifyKey(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, errors.Errorf("unknown kid %q", kid)
+	}
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, errors.Errorf("unexpected signing method %s of kid %s", t.Method.Alg(), kid)
+	}
+	return key.public, nil
+}
+
+// JWK public key in JSON web key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKS set of JWK
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwks returns public keys sorted by kid
+func (s *jwtKeySet) jwks() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(s.keys))}
+	for _, key := range s.keys {
+		jwk := JWK{KeyID: key.id, Use: "sig", Algorithm: key.method.Alg()}
+		b64 := base64.RawURLEncoding.EncodeToString
+		switch k := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = b64(k.N.Bytes())
+			jwk.E = b64(big.NewInt(int64(k.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (k.Curve.Params().BitSize + 7) / 8
+			jwk.KeyType = "EC"
+			jwk.Curve = k.Curve.Params().Name
+			jwk.X = b64(k.X.FillBytes(make([]byte, size)))
+			jwk.Y = b64(k.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = b64(k)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].KeyID < jwks.Keys[j].KeyID })
+	return jwks
+}
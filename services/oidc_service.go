@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"golang.org/x/oauth2"
+)
+
+// ExternalIdentity identity of user asserted by id token of provider
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OIDCFlow secrets of an authorization started by AuthCodeURL, kept by user agent until callback
+type OIDCFlow struct {
+	// State binds callback to the user agent started the flow
+	State string `json:"state"`
+	// Nonce binds id token to the flow
+	Nonce string `json:"nonce"`
+	// Verifier code verifier of PKCE (RFC 7636)
+	Verifier string `json:"verifier"`
+}
+
+// oidcProvider discovered provider
+type oidcProvider struct {
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// OIDCService relying party of OpenID Connect providers in config, authorization code flow with PKCE
+type OIDCService struct {
+	cfg *config.Config
+	// client http client of calls to providers
+	client *http.Client
+	mu     sync.Mutex
+	// providers discovered providers by name, discovery is lazy and retried until it succeeds
+	providers map[string]*oidcProvider
+}
+
+// NewOIDCService creates a new oidc service, providers are called by client
+func NewOIDCService(cfg *config.Config, client *http.Client) *OIDCService {
+	return &OIDCService{
+		cfg:       cfg,
+		client:    client,
+		providers: map[string]*oidcProvider{},
+	}
+}
+
+// provider returns discovered provider of name, NotFound error if it's not configured.
+// discovery runs without the lock so that a slow issuer doesn't block sign in by other providers,
+// concurrent discoveries of the same provider may happen until one of them is stored
+func (s *OIDCService) provider(ctx context.Context, name string) (*oidcProvider, error) {
+	s.mu.Lock()
+	p, ok := s.providers[name]
+	s.mu.Unlock()
+	if ok {
+		return p, nil
+	}
+	pc, ok := s.cfg.OIDC.Providers[name]
+	if !ok {
+		return nil, errors.CodeErrorf(errors.NotFound, "oidc provider %s not found", name)
+	}
+	discovered, err := oidc.NewProvider(oidc.ClientContext(ctx, s.client), pc.Issuer)
+	if err != nil {
+		return nil, errors.CodeWrapf(errors.UpstreamError, err, "discover oidc provider %s", name)
+	}
+	p = &oidcProvider{
+		oauth2: oauth2.Config{
+			ClientID:     pc.ClientID,
+			ClientSecret: pc.ClientSecret,
+			Endpoint:     discovered.Endpoint(),
+			RedirectURL:  strings.TrimSuffix(s.cfg.OIDC.RedirectBaseURL, "/") + "/auth/oidc/" + name + "/callback",
+			Scopes:       append([]string{oidc.ScopeOpenID}, pc.Scopes...),
+		},
+		verifier: discovered.Verifier(&oidc.Config{ClientID: pc.ClientID}),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.providers[name]; ok {
+		return stored, nil
+	}
+	s.providers[name] = p
+	return p, nil
+}
+
+// AuthCodeURL starts authorization at provider of name,
+// returns url to redirect user agent to and the flow to be given back to Callback
+func (s *OIDCService) AuthCodeURL(ctx context.Context, name string) (string, OIDCFlow, error) {
+	p, err := s.provider(ctx, name)
+	if err != nil {
+		return "", OIDCFlow{}, err
+	}
+	var flow OIDCFlow
+	for _, v := range []*string{&flow.State, &flow.Nonce, &flow.Verifier} {
+		if *v, err = randomString(); err != nil {
+			return "", flow, err
+		}
+	}
+	url := p.oauth2.AuthCodeURL(flow.State,
+		oidc.Nonce(flow.Nonce),
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge(flow.Verifier)),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+	return url, flow, nil
+}
+
+// Callback exchanges authorization code of flow for id token, returns identity asserted by it.
+// State of callback must have been checked against flow by caller.
+func (s *OIDCService) Callback(ctx context.Context, name, code string, flow OIDCFlow) (ExternalIdentity, error) {
+	p, err := s.provider(ctx, name)
+	if err != nil {
+		return ExternalIdentity{}, err
+	}
+	ctx = oidc.ClientContext(ctx, s.client)
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", flow.Verifier))
+	if err != nil {
+		return ExternalIdentity{}, errors.CodeWrap(errors.AuthError, err, "exchange authorization code")
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return ExternalIdentity{}, errors.CodeErrorf(errors.AuthError, "no id_token in token response")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return ExternalIdentity{}, errors.CodeWrap(errors.AuthError, err, "verify id token")
+	}
+	if idToken.Nonce != flow.Nonce {
+		return ExternalIdentity{}, errors.CodeErrorf(errors.AuthError, "unexpected nonce of id token")
+	}
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return ExternalIdentity{}, errors.CodeWrap(errors.AuthError, err, "parse id token claims")
+	}
+	return ExternalIdentity{
+		Provider:      name,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
+
+// codeChallenge returns S256 code challenge of verifier
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generate random string")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// mockOIDCProvider OpenID Connect provider issuing id tokens for codes of authorization requests
+type mockOIDCProvider struct {
+	*httptest.Server
+	t   *testing.T
+	key *rsa.PrivateKey
+	// requests authorization requests by code
+	requests map[string]url.Values
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockOIDCProvider{t: t, key: key, requests: map[string]url.Values{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		m.writeJSON(w, map[string]interface{}{
+			"issuer":                                m.URL,
+			"authorization_endpoint":                m.URL + "/authorize",
+			"token_endpoint":                        m.URL + "/token",
+			"jwks_uri":                              m.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		keys := &jwtKeySet{keys: map[string]*jwtKey{
+			"mock": {id: "mock", method: jwt.SigningMethodRS256, public: key.Public()},
+		}}
+		m.writeJSON(w, keys.jwks())
+	})
+	mux.HandleFunc("/token", m.token)
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// authorize records authorization request of url as if user signed in and was given code
+func (m *mockOIDCProvider) authorize(authURL, code string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	m.requests[code] = u.Query()
+}
+
+func (m *mockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	req, ok := m.requests[r.FormValue("code")]
+	if !ok || codeChallenge(r.FormValue("code_verifier")) != req.Get("code_challenge") {
+		w.WriteHeader(http.StatusBadRequest)
+		m.writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	clientID, _, _ := r.BasicAuth()
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            m.URL,
+		"aud":            clientID,
+		"sub":            "subject-1",
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Minute).Unix(),
+		"nonce":          req.Get("nonce"),
+		"email":          "Bob@Example.com",
+		"email_verified": true,
+		"name":           "bob",
+	})
+	token.Header["kid"] = "mock"
+	idToken, err := token.SignedString(m.key)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	m.writeJSON(w, map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+func (m *mockOIDCProvider) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		m.t.Error(err)
+	}
+}
+
+func TestOIDC(t *testing.T) {
+	ctx := context.Background()
+	mock := newMockOIDCProvider(t)
+	cfg := config.DefaultConfig()
+	cfg.OIDC.RedirectBaseURL = "https://app.example.com/"
+	cfg.OIDC.Providers["mock"] = config.OIDCProvider{
+		Issuer: mock.URL, ClientID: "client", ClientSecret: "secret", Scopes: []string{"email", "profile"},
+	}
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	cfg.OIDC.Providers["down"] = config.OIDCProvider{Issuer: down.URL, ClientID: "client"}
+	s := NewOIDCService(cfg, http.DefaultClient)
+
+	authURL, flow, err := s.AuthCodeURL(ctx, "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.authorize(authURL, "code")
+	q := mock.requests["code"]
+	want := map[string]string{
+		"client_id":             "client",
+		"redirect_uri":          "https://app.example.com/auth/oidc/mock/callback",
+		"response_type":         "code",
+		"scope":                 "openid email profile",
+		"state":                 flow.State,
+		"nonce":                 flow.Nonce,
+		"code_challenge":        codeChallenge(flow.Verifier),
+		"code_challenge_method": "S256",
+	}
+	for k, v := range want {
+		if q.Get(k) != v {
+			t.Errorf("%s of authorization request: got %q, want %q", k, q.Get(k), v)
+		}
+	}
+
+	identity, err := s.Callback(ctx, "mock", "code", flow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity != (ExternalIdentity{"mock", "subject-1", "Bob@Example.com", true, "bob"}) {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+
+	wrongVerifier, wrongNonce := flow, flow
+	wrongVerifier.Verifier = "other"
+	wrongNonce.Nonce = "other"
+	for name, f := range map[string]OIDCFlow{"verifier": wrongVerifier, "nonce": wrongNonce} {
+		if _, err := s.Callback(ctx, "mock", "code", f); !errors.IsCodeErrorEqual(err, errors.AuthError) {
+			t.Error("wrong", name, "should fail with auth error", err)
+		}
+	}
+	if _, err := s.Callback(ctx, "mock", "unknown", flow); !errors.IsCodeErrorEqual(err, errors.AuthError) {
+		t.Error("unknown code should fail with auth error", err)
+	}
+	if _, _, err := s.AuthCodeURL(ctx, "other"); !errors.IsCodeErrorEqual(err, errors.NotFound) {
+		t.Error("unknown provider should be not found", err)
+	}
+	if _, _, err := s.AuthCodeURL(ctx, "down"); !errors.IsCodeErrorEqual(err, errors.UpstreamError) {
+		t.Error("discovery failure should be upstream error", err)
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/dean2032/go-project-layout/constants"
+	"github.com/gin-gonic/gin"
+)
+
+// principalKey key of principal in context.Context
+type principalKey struct{}
+
+// Principal the authenticated caller of request, a user or an api key
+type Principal struct {
+	// UserID is 0 if authenticated by api key
+	UserID uint
+	// APIKeyID is 0 if authenticated by access token
+	APIKeyID uint
+	Roles    []string
+	Scopes   []string
+	// TokenID jti of access token
+	TokenID string
+	// ExpiresAt expiry of access token
+	ExpiresAt time.Time
+}
+
+// Principal returns principal of access token claims, scope is space separated as RFC 8693
+func (c *Claims) Principal() *Principal {
+	p := &Principal{
+		UserID:  c.UserID,
+		Roles:   c.Roles,
+		Scopes:  strings.Fields(c.Scope),
+		TokenID: c.ID,
+	}
+	if c.ExpiresAt != nil {
+		p.ExpiresAt = c.ExpiresAt.Time
+	}
+	return p
+}
+
+// HasRole returns whether principal has role
+func (p *Principal) HasRole(role string) bool {
+	return contains(p.Roles, role)
+}
+
+// HasScope returns whether principal has scope
+func (p *Principal) HasScope(scope string) bool {
+	return contains(p.Scopes, scope)
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPrincipal returns a copy of ctx with principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// SetPrincipal sets principal to gin context and context of its request
+func SetPrincipal(c *gin.Context, p *Principal) {
+	c.Set(constants.Principal, p)
+	c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), p))
+}
+
+// PrincipalFromContext returns principal of ctx, which is *gin.Context or context of request,
+// ok is false if request is not authenticated
+func PrincipalFromContext(ctx context.Context) (p *Principal, ok bool) {
+	if gc, isGin := ctx.(*gin.Context); isGin {
+		if v, exists := gc.Get(constants.Principal); exists {
+			return v.(*Principal), true
+		}
+		if gc.Request == nil {
+			return nil, false
+		}
+		ctx = gc.Request.Context()
+	}
+	p, ok = ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
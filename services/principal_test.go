@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestPrincipalFromContext(t *testing.T) {
+	claims := &Claims{
+		UserID:           7,
+		Roles:            []string{"admin"},
+		Scope:            "users:read users:write",
+		RegisteredClaims: jwt.RegisteredClaims{ID: "jti"},
+	}
+	p := claims.Principal()
+	if !p.HasRole("admin") || !p.HasScope("users:write") || p.HasScope("users") || p.TokenID != "jti" {
+		t.Errorf("unexpected principal %+v", p)
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	if _, ok := PrincipalFromContext(c); ok {
+		t.Error("request is not authenticated")
+	}
+	SetPrincipal(c, p)
+	for name, ctx := range map[string]context.Context{"gin": c, "request": c.Request.Context()} {
+		if got, ok := PrincipalFromContext(ctx); !ok || got != p {
+			t.Error(name, "principal not found", got)
+		}
+	}
+}
@@ -1,50 +1,334 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/dean2032/go-project-layout/config"
 	"github.com/dean2032/go-project-layout/models"
 	"github.com/dean2032/go-project-layout/repo"
+	"github.com/dean2032/go-project-layout/utils"
+	"github.com/dean2032/go-project-layout/utils/cache"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/metrics"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// userCacheNamespace namespace of user cache keys
+	userCacheNamespace = "user:"
+
+	// DefaultRole role assigned to registered users
+	DefaultRole = "member"
+	// maxNameLen max length of user name
+	maxNameLen = 20
 )
 
 // UserService service layer
 type UserService struct {
 	repository *repo.UserRepository
+	roles      *repo.RoleRepository
+	identities *repo.UserIdentityRepository
+	cache      *cache.Loader
+	// tx is set by WithTx, reads in transaction bypass cache
+	tx *gorm.DB
 }
 
-// NewUserService creates a new userservice
-func NewUserService(repository *repo.UserRepository) *UserService {
-	return &UserService{
+// NewUserService creates a new userservice, hits and misses of its cache are recorded by m
+func NewUserService(
+	repository *repo.UserRepository,
+	roles *repo.RoleRepository,
+	identities *repo.UserIdentityRepository,
+	c cache.Cache,
+	cfg *config.Config,
+	m *metrics.Metrics,
+) *UserService {
+	s := &UserService{
 		repository: repository,
+		roles:      roles,
+		identities: identities,
+		cache:      cache.NewLoader(c, userCacheNamespace, cfg.Cache.TTLDuration()),
 	}
+	m.RegisterCache("user", s.cache.Stats)
+	return s
 }
 
-// WithTx delegates transaction to repository database
+// WithTx returns a copy of service delegating transaction to repository database
 func (s *UserService) WithTx(txHandle *gorm.DB) *UserService {
-	s.repository = s.repository.WithTx(txHandle)
-	return s
+	return &UserService{
+		repository: s.repository.WithTx(txHandle),
+		roles:      s.roles.WithTx(txHandle),
+		identities: s.identities.WithTx(txHandle),
+		cache:      s.cache,
+		tx:         txHandle,
+	}
 }
 
-// GetOneUser gets one user
+// CacheStats returns hit and miss counters of user cache
+func (s *UserService) CacheStats() cache.Stats {
+	return s.cache.Stats()
+}
+
+func userCacheKey(id uint) string {
+	return fmt.Sprintf("%d", id)
+}
+
+// GetOneUser gets one user, NotFound error is returned if not exists, which is not cached
 func (s *UserService) GetOneUser(id uint) (user models.User, err error) {
-	return user, s.repository.Find(&user, id).Error
+	if s.tx != nil {
+		return s.findUser(id)
+	}
+	err = s.cache.Load(context.Background(), userCacheKey(id), &user, func() (interface{}, error) {
+		return s.findUser(id)
+	})
+	return user, err
 }
 
-// GetAllUser get all the user
-func (s *UserService) GetAllUser() (users []models.User, err error) {
-	return users, s.repository.Find(&users).Error
+// ListUsers gets a page of users matching q, pages are not cached
+func (s *UserService) ListUsers(q repo.ListQuery) (users []models.User, pagination repo.Pagination, err error) {
+	users = []models.User{}
+	pagination, err = repo.List(s.repository.DB, q, &users)
+	// invalid queries are input errors already
+	if err != nil && errors.Err2Code(err) == errors.UnknownError {
+		err = errors.CodeWrap(errors.DBError, err, "list users")
+	}
+	return users, pagination, err
 }
 
 // CreateUser call to create the user
 func (s *UserService) CreateUser(user models.User) error {
-	return s.repository.Create(&user).Error
+	if err := s.repository.Create(&user).Error; err != nil {
+		return errors.CodeWrap(errors.DBError, err, "create user")
+	}
+	return nil
+}
+
+// Register creates user with email and password and assigns DefaultRole to it,
+// Conflict error is returned if email is registered
+func (s *UserService) Register(email, name, password string) (models.User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return models.User{}, errors.CodeWrap(errors.InputError, err, "hash password")
+	}
+	email = normalizeEmail(email)
+	user := models.User{Name: name, Email: &email, PasswordHash: string(hash)}
+	if err := s.repository.Create(&user).Error; err != nil {
+		if repo.IsDuplicateKey(err) {
+			return user, errors.CodeErrorf(errors.Conflict, "email %s is already registered", email)
+		}
+		return user, errors.CodeWrap(errors.DBError, err, "create user")
+	}
+	if err := s.roles.AssignRole(user.ID, DefaultRole); err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+// LinkIdentity returns user of external identity. Identities not linked yet are linked to
+// the user of the same email if provider verified it, otherwise a user without password is created.
+func (s *UserService) LinkIdentity(identity ExternalIdentity) (models.User, error) {
+	if user, ok, err := s.linkedUser(identity, false); err != nil || ok {
+		return user, err
+	}
+
+	var user models.User
+
+	if identity.Email == "" {
+		return user, errors.CodeErrorf(errors.AuthError, "email of %s identity is required", identity.Provider)
+	}
+	email := normalizeEmail(identity.Email)
+	if err := s.repository.Where("email = ?", email).Limit(1).Find(&user).Error; err != nil {
+		return user, errors.CodeWrap(errors.DBError, err, "find user")
+	}
+	switch {
+	case user.ID != 0 && !identity.EmailVerified:
+		// an unverified email may be of anyone
+		return models.User{}, errors.CodeErrorf(errors.Conflict,
+			"email %s is registered, sign in with password to link %s", email, identity.Provider)
+	case user.ID == 0:
+		user = models.User{Name: truncate(identity.Name, maxNameLen), Email: &email}
+		if err := s.repository.Create(&user).Error; err != nil {
+			if repo.IsDuplicateKey(err) {
+				return user, errors.CodeErrorf(errors.Conflict, "email %s is already registered", email)
+			}
+			return user, errors.CodeWrap(errors.DBError, err, "create user")
+		}
+		if err := s.roles.AssignRole(user.ID, DefaultRole); err != nil {
+			return user, err
+		}
+	}
+	if err := s.identities.Create(&models.UserIdentity{
+		UserID:   user.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    email,
+	}).Error; err != nil {
+		if !repo.IsDuplicateKey(err) {
+			return user, errors.CodeWrap(errors.DBError, err, "link identity")
+		}
+		// linked by a concurrent sign in of the same identity
+		if linkedUser, ok, err := s.linkedUser(identity, true); err != nil || ok {
+			return linkedUser, err
+		}
+		return user, errors.CodeErrorf(errors.Conflict, "%s identity is being linked, sign in again", identity.Provider)
+	}
+	return user, nil
+}
+
+// linkedUser returns user linked to identity, ok is false if the identity is not linked.
+// latest reads the committed link by a locking read instead of the snapshot of transaction
+func (s *UserService) linkedUser(identity ExternalIdentity, latest bool) (user models.User, ok bool, err error) {
+	var linked models.UserIdentity
+	db := s.identities.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject)
+	if latest {
+		db = db.Clauses(clause.Locking{Strength: "SHARE"})
+	}
+	if err := db.Limit(1).Find(&linked).Error; err != nil {
+		return user, false, errors.CodeWrap(errors.DBError, err, "find identity")
+	}
+	if linked.ID == 0 {
+		return user, false, nil
+	}
+	if err := s.repository.Find(&user, linked.UserID).Error; err != nil {
+		return user, false, errors.CodeWrap(errors.DBError, err, "find user of identity")
+	}
+	if user.ID == 0 {
+		return user, false, errors.CodeErrorf(errors.AuthError, "user of identity not found")
+	}
+	return user, true, nil
+}
+
+// truncate returns the first n runes of s
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+// Authenticate verifies email and password, AuthError is returned if they don't match any user
+func (s *UserService) Authenticate(email, password string) (models.User, error) {
+	var user models.User
+	// users are looked up from database, password hash is not cached
+	if err := s.repository.Where("email = ?", normalizeEmail(email)).Limit(1).Find(&user).Error; err != nil {
+		return user, errors.CodeWrap(errors.DBError, err, "find user")
+	}
+	hash := []byte(user.PasswordHash)
+	if user.ID == 0 || len(hash) == 0 {
+		// compare with a dummy hash anyway, so that unknown emails can't be told by response time
+		hash = dummyPasswordHash()
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || user.PasswordHash == "" {
+		return models.User{}, errors.CodeErrorf(errors.AuthError, "invalid email or password")
+	}
+	return user, nil
 }
 
-// UpdateUser updates the user
-func (s *UserService) UpdateUser(user models.User) error {
-	return s.repository.Save(&user).Error
+var (
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	})
+	return dummyHash
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// userWritableColumns columns of user can be changed by update,
+// id, created_at, updated_at and version are managed by the service
+var userWritableColumns = []string{"name", "email", "age", "birthday", "member_number"}
+
+// ReplaceUser replaces writable fields of the user of user.ID. version is the version of
+// user the change is based on, Conflict error is returned if the user has been changed since then.
+// The updated user is returned.
+func (s *UserService) ReplaceUser(user models.User, version uint) (models.User, error) {
+	id := user.ID
+	user.Version = version + 1
+	// updated_at is set by gorm although not selected
+	result := s.repository.Model(&user).
+		Select(append(userWritableColumns, "version")).
+		Where("id = ? AND version = ?", id, version).
+		Updates(&user)
+	if result.Error != nil {
+		return user, errors.CodeWrap(errors.DBError, result.Error, "update user")
+	}
+	if result.RowsAffected == 0 {
+		current, err := s.findUser(id)
+		if err != nil {
+			return current, err
+		}
+		return current, errors.CodeErrorf(errors.Conflict,
+			"user %d has been changed, version %d is stale, current version is %d", id, version, current.Version)
+	}
+	s.invalidate(userCacheKey(id))
+	return s.findUser(id)
+}
+
+// PatchUser applies JSON merge patch to writable fields of the user, see ReplaceUser for version
+func (s *UserService) PatchUser(id uint, patch []byte, version uint) (models.User, error) {
+	current, err := s.findUser(id)
+	if err != nil {
+		return current, err
+	}
+	if current.Version != version {
+		return current, errors.CodeErrorf(errors.Conflict,
+			"user %d has been changed, version %d is stale, current version is %d", id, version, current.Version)
+	}
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return current, errors.WithStack(err)
+	}
+	merged, err := utils.MergePatch(doc, patch)
+	if err != nil {
+		return current, err
+	}
+	var user models.User
+	if err := json.Unmarshal(merged, &user); err != nil {
+		return current, errors.CodeWrap(errors.InputError, err, "invalid user patch")
+	}
+	user.ID = id
+	return s.ReplaceUser(user, version)
+}
+
+// findUser finds user by id bypassing cache, NotFound error is returned if not exists
+func (s *UserService) findUser(id uint) (user models.User, err error) {
+	if err := s.repository.Limit(1).Find(&user, id).Error; err != nil {
+		return user, errors.CodeWrap(errors.DBError, err, "find user")
+	}
+	if user.ID == 0 {
+		return user, errors.CodeErrorf(errors.NotFound, "user %d not found", id)
+	}
+	return user, nil
 }
 
 // DeleteUser deletes the user
 func (s *UserService) DeleteUser(id uint) error {
-	return s.repository.Delete(&models.User{}, id).Error
+	if err := s.repository.Delete(&models.User{}, id).Error; err != nil {
+		return err
+	}
+	s.invalidate(userCacheKey(id))
+	return nil
+}
+
+// invalidate deletes cached keys at once, and again after commit in transaction,
+// since values of other requests may be cached before the commit
+func (s *UserService) invalidate(keys ...string) {
+	ctx := context.Background()
+	s.cache.Invalidate(ctx, keys...)
+	if s.tx != nil {
+		repo.AfterCommit(s.tx, func() {
+			s.cache.Invalidate(ctx, keys...)
+		})
+	}
 }
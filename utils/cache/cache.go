@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/dean2032/go-project-layout/utils/errors"
+)
+
+// ErrMiss is returned by Cache.Get if key not found or expired
+var ErrMiss = errors.New("cache miss")
+
+// Cache stores values by key with ttl
+type Cache interface {
+	// Get returns value of key, ErrMiss if not found
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value of key, ttl <= 0 means never expire
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete deletes keys, missing keys are ignored
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// nopCache caches nothing
+type nopCache struct{}
+
+// NewNopCache returns a cache caching nothing
+func NewNopCache() Cache {
+	return nopCache{}
+}
+
+// Get ...
+func (nopCache) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, ErrMiss
+}
+
+// Set ...
+func (nopCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return nil
+}
+
+// Delete ...
+func (nopCache) Delete(ctx context.Context, keys ...string) error {
+	return nil
+}
@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestMemoryCacheLRU(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(2)
+	_ = c.Set(ctx, "a", []byte("1"), 0)
+	_ = c.Set(ctx, "b", []byte("2"), 0)
+	// a becomes most recently used, b is evicted
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Set(ctx, "c", []byte("3"), 0)
+	if _, err := c.Get(ctx, "b"); err != ErrMiss {
+		t.Error("b should be evicted")
+	}
+	if c.Len() != 2 {
+		t.Error("unexpected len", c.Len())
+	}
+	_ = c.Delete(ctx, "a", "missing")
+	if _, err := c.Get(ctx, "a"); err != ErrMiss {
+		t.Error("a should be deleted")
+	}
+}
+
+func TestMemoryCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10)
+	_ = c.Set(ctx, "a", []byte("1"), 10*time.Millisecond)
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, err := c.Get(ctx, "a"); err != ErrMiss {
+		t.Error("a should be expired")
+	}
+}
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	c := NewRedisCache(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "test:")
+	if _, err := c.Get(ctx, "a"); err != ErrMiss {
+		t.Fatal("expect miss", err)
+	}
+	_ = c.Set(ctx, "a", []byte("1"), time.Minute)
+	if !mr.Exists("test:a") {
+		t.Error("key should be prefixed")
+	}
+	if v, err := c.Get(ctx, "a"); err != nil || string(v) != "1" {
+		t.Error(string(v), err)
+	}
+	_ = c.Delete(ctx, "a")
+	if mr.Exists("test:a") {
+		t.Error("a should be deleted")
+	}
+}
+
+func TestLoader(t *testing.T) {
+	ctx := context.Background()
+	l := NewLoader(NewMemoryCache(10), "ns:", time.Minute)
+
+	var calls int32
+	start := make(chan struct{})
+	load := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-start
+		return []string{"a", "b"}, nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var v []string
+			if err := l.Load(ctx, "k", &v, load); err != nil || len(v) != 2 {
+				t.Error(v, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(start)
+	wg.Wait()
+	if calls != 1 {
+		t.Error("concurrent misses should be loaded once, got", calls)
+	}
+
+	var v []string
+	if err := l.Load(ctx, "k", &v, load); err != nil {
+		t.Fatal(err)
+	}
+	if stats := l.Stats(); stats.Hits != 1 || stats.Misses != 10 {
+		t.Error("unexpected stats", stats)
+	}
+
+	l.Invalidate(ctx, "k")
+	loadErr := errors.New("load error")
+	if err := l.Load(ctx, "k", &v, func() (interface{}, error) { return nil, loadErr }); err != loadErr {
+		t.Error("load error should be returned", err)
+	}
+}
+
+func TestLoaderInvalidateInFlight(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10)
+	l := NewLoader(c, "ns:", time.Minute)
+
+	loading, release := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var v string
+		_ = l.Load(ctx, "k", &v, func() (interface{}, error) {
+			close(loading)
+			<-release
+			return "stale", nil
+		})
+	}()
+	<-loading
+	l.Invalidate(ctx, "k")
+	close(release)
+	<-done
+
+	if _, err := c.Get(ctx, "ns:k"); err != ErrMiss {
+		t.Error("value loaded before invalidation should not be cached", err)
+	}
+	var v string
+	if err := l.Load(ctx, "k", &v, func() (interface{}, error) { return "fresh", nil }); err != nil || v != "fresh" {
+		t.Error("unexpected value", v, err)
+	}
+	if _, err := c.Get(ctx, "ns:k"); err != nil {
+		t.Error("value loaded after invalidation should be cached", err)
+	}
+}
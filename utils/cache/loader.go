@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// Stats counters of Loader
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// Loader reads through cache: values are json encoded, concurrent loads of
+// the same missing key are deduplicated. Cache errors are logged and treated as miss,
+// so the cache never fails a read.
+type Loader struct {
+	cache     Cache
+	namespace string
+	ttl       time.Duration
+	group     singleflight.Group
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	// generations of keys bumped by Invalidate, loads started before are not cached.
+	// keys share counters by hash, a collision only skips caching of a load
+	generations [256]atomic.Uint64
+}
+
+// generation returns counter of generation of key
+func (l *Loader) generation(key string) *atomic.Uint64 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &l.generations[h.Sum32()%uint32(len(l.generations))]
+}
+
+// NewLoader creates loader storing keys under namespace in cache
+func NewLoader(cache Cache, namespace string, ttl time.Duration) *Loader {
+	return &Loader{
+		cache:     cache,
+		namespace: namespace,
+		ttl:       ttl,
+	}
+}
+
+// Load unmarshals cached value of key into dst, on miss it calls load and caches its result.
+// Errors of load are returned and not cached.
+func (l *Loader) Load(ctx context.Context, key string, dst interface{}, load func() (interface{}, error)) error {
+	key = l.namespace + key
+	data, err := l.cache.Get(ctx, key)
+	if err == nil {
+		if err = json.Unmarshal(data, dst); err == nil {
+			l.hits.Add(1)
+			return nil
+		}
+	}
+	if err != ErrMiss {
+		logging.CtxLogger(ctx).Warn("cache get fail", zap.String("key", key), zap.Error(err))
+	}
+	l.misses.Add(1)
+
+	v, err, _ := l.group.Do(key, func() (interface{}, error) {
+		gen := l.generation(key)
+		started := gen.Load()
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if gen.Load() != started {
+			// invalidated while loading, the value may be read before the change
+			return data, nil
+		}
+		if err := l.cache.Set(ctx, key, data, l.ttl); err != nil {
+			logging.CtxLogger(ctx).Warn("cache set fail", zap.String("key", key), zap.Error(err))
+		}
+		if gen.Load() != started {
+			// invalidated between the check and set
+			l.delete(ctx, key)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return err
+	}
+	// each caller unmarshals its own copy
+	return json.Unmarshal(v.([]byte), dst)
+}
+
+// Invalidate deletes cached values of keys
+func (l *Loader) Invalidate(ctx context.Context, keys ...string) {
+	namespaced := make([]string, 0, len(keys))
+	for _, key := range keys {
+		key = l.namespace + key
+		namespaced = append(namespaced, key)
+		// loads in flight don't cache their values, following reads load again instead of joining them
+		l.generation(key).Add(1)
+		l.group.Forget(key)
+	}
+	l.delete(ctx, namespaced...)
+}
+
+func (l *Loader) delete(ctx context.Context, keys ...string) {
+	if err := l.cache.Delete(ctx, keys...); err != nil {
+		logging.CtxLogger(ctx).Error("cache invalidate fail", zap.Strings("keys", keys), zap.Error(err))
+	}
+}
+
+// Stats returns hit and miss counters
+func (l *Loader) Stats() Stats {
+	return Stats{
+		Hits:   l.hits.Load(),
+		Misses: l.misses.Load(),
+	}
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	key      string
+	value    []byte
+	expireAt time.Time
+}
+
+// MemoryCache in-memory LRU cache with ttl
+type MemoryCache struct {
+	size  int
+	mu    sync.Mutex
+	lru   *list.List
+	items map[string]*list.Element
+}
+
+// NewMemoryCache creates LRU cache holding at most size entries
+func NewMemoryCache(size int) *MemoryCache {
+	if size <= 0 {
+		size = 1
+	}
+	return &MemoryCache{
+		size:  size,
+		lru:   list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// Get ...
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	entry := elem.Value.(*memoryEntry)
+	if !entry.expireAt.IsZero() && time.Now().After(entry.expireAt) {
+		c.remove(elem)
+		return nil, ErrMiss
+	}
+	c.lru.MoveToFront(elem)
+	return entry.value, nil
+}
+
+// Set ...
+func (c *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*memoryEntry)
+		entry.value = value
+		entry.expireAt = expireAt
+		c.lru.MoveToFront(elem)
+		return nil
+	}
+	c.items[key] = c.lru.PushFront(&memoryEntry{key: key, value: value, expireAt: expireAt})
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+	return nil
+}
+
+// Delete ...
+func (c *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if elem, ok := c.items[key]; ok {
+			c.remove(elem)
+		}
+	}
+	return nil
+}
+
+// Len returns count of entries, including expired ones not evicted yet
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *MemoryCache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.items, elem.Value.(*memoryEntry).key)
+}
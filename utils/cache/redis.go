@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisCache cache backed by redis, shared by all instances of service
+type RedisCache struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisCache creates cache storing keys with prefix in redis
+func NewRedisCache(client redis.UniversalClient, prefix string) *RedisCache {
+	return &RedisCache{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Get ...
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrMiss
+	}
+	return value, errors.WithStack(err)
+}
+
+// Set ...
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return errors.WithStack(c.client.Set(ctx, c.prefix+key, value, ttl).Err())
+}
+
+// Delete ...
+func (c *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, c.prefix+key)
+	}
+	return errors.WithStack(c.client.Del(ctx, prefixed...).Err())
+}
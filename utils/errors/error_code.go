@@ -12,6 +12,22 @@ var (
 	DBError = NewCodeError(3, "DB error")
 	// NotFound ...
 	NotFound = NewCodeError(4, "Not found")
+	// ConfigError ...
+	ConfigError = NewCodeError(5, "Config error")
+	// Conflict ...
+	Conflict = NewCodeError(6, "Conflict")
+	// Forbidden ...
+	Forbidden = NewCodeError(7, "Forbidden")
+	// MethodNotAllowed ...
+	MethodNotAllowed = NewCodeError(8, "Method not allowed")
+	// TooManyRequests ...
+	TooManyRequests = NewCodeError(9, "Too many requests")
+	// RequestTooLarge ...
+	RequestTooLarge = NewCodeError(10, "Request too large")
+	// UnsupportedMediaType ...
+	UnsupportedMediaType = NewCodeError(11, "Unsupported media type")
+	// UpstreamError ...
+	UpstreamError = NewCodeError(12, "Upstream error")
 	// UnknownError ...
 	UnknownError = NewCodeError(100, "Unknown error")
 )
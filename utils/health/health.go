@@ -0,0 +1,179 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// Module provides health checker registry of the app
+var Module = fx.Provide(NewRegistry)
+
+// url paths of probes
+const (
+	// LivenessPath responds ok while the process is serving
+	LivenessPath = "/healthz"
+	// ReadinessPath responds ok if all checks pass and the app is not shutting down
+	ReadinessPath = "/readyz"
+)
+
+// status of checks and reports
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Checker checks a dependency, returns nil if it's ready
+type Checker func(ctx context.Context) error
+
+// Result result of a check
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// DurationMS milliseconds the check took
+	DurationMS int64     `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// Report results of all checks, Status is down if any of them is down
+type Report struct {
+	Status       string            `json:"status"`
+	ShuttingDown bool              `json:"shutting_down,omitempty"`
+	Checks       map[string]Result `json:"checks,omitempty"`
+}
+
+// Registry checkers of dependencies, components register their checkers on creation
+type Registry struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+	// shuttingDown fails readiness without running checks
+	shuttingDown atomic.Bool
+
+	// mu serializes checks, concurrent probes share results instead of running checks again
+	mu       sync.Mutex
+	checkers map[string]Checker
+	results  map[string]Result
+}
+
+// NewRegistry creates registry checking with timeout and cache ttl of cfg.Health
+func NewRegistry(cfg *config.Config) *Registry {
+	return &Registry{
+		timeout:  cfg.Health.TimeoutDuration(),
+		cacheTTL: cfg.Health.CacheTTLDuration(),
+		checkers: map[string]Checker{},
+		results:  map[string]Result{},
+	}
+}
+
+// Register adds checker of name, it replaces the one registered with the same name
+func (r *Registry) Register(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkers[name] = checker
+	delete(r.results, name)
+}
+
+// ShuttingDown fails readiness since now, called when graceful shutdown begins
+func (r *Registry) ShuttingDown() {
+	if !r.shuttingDown.Swap(true) {
+		logging.Info("readiness failing for shutdown")
+	}
+}
+
+// Check runs checks concurrently each with timeout, results younger than cache ttl are reused
+func (r *Registry) Check(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Checks: map[string]Result{}}
+	if r.shuttingDown.Load() {
+		report.Status = StatusDown
+		report.ShuttingDown = true
+		return report
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var (
+		wg        sync.WaitGroup
+		resultsMu sync.Mutex
+	)
+	now := time.Now()
+	for name, checker := range r.checkers {
+		if result, ok := r.results[name]; ok && now.Sub(result.CheckedAt) < r.cacheTTL {
+			continue
+		}
+		wg.Add(1)
+		go func(name string, checker Checker) {
+			defer wg.Done()
+			result := r.run(ctx, name, checker)
+			resultsMu.Lock()
+			r.results[name] = result
+			resultsMu.Unlock()
+		}(name, checker)
+	}
+	wg.Wait()
+	for name := range r.checkers {
+		result := r.results[name]
+		report.Checks[name] = result
+		if result.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// run runs checker with timeout, the checker is abandoned if it doesn't return in time
+func (r *Registry) run(ctx context.Context, name string, checker Checker) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- checker(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = errors.Errorf("check timed out after %s", r.timeout)
+	}
+	result := Result{Status: StatusUp, DurationMS: time.Since(start).Milliseconds(), CheckedAt: start}
+	if err != nil {
+		logging.Warn("health check fail", zap.String("check", name), zap.Error(err))
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler responds ok as long as the process can serve requests
+func (r *Registry) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, Report{Status: StatusUp})
+	})
+}
+
+// ReadinessHandler responds report of checks, 503 if it's down
+func (r *Registry) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Check(req.Context())
+		status := http.StatusOK
+		if report.Status != StatusUp {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
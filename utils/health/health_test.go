@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/errors"
+)
+
+func TestRegistry(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Health.Timeout = 1
+	cfg.Health.CacheTTL = 60
+	r := NewRegistry(cfg)
+	var calls atomic.Int32
+	var failing atomic.Bool
+	r.Register("db", func(ctx context.Context) error {
+		calls.Add(1)
+		if failing.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	ready := func() (int, Report) {
+		w := httptest.NewRecorder()
+		r.ReadinessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, ReadinessPath, nil))
+		var report Report
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code, report
+	}
+
+	if code, report := ready(); code != http.StatusOK || report.Checks["db"].Status != StatusUp {
+		t.Error("should be ready", code, report)
+	}
+	// cached
+	failing.Store(true)
+	if code, _ := ready(); code != http.StatusOK || calls.Load() != 1 {
+		t.Error("result should be cached", code, calls.Load())
+	}
+	// registering again drops cached result
+	r.Register("slow", func(ctx context.Context) error {
+		time.Sleep(3 * time.Second)
+		return nil
+	})
+	r.Register("db", r.checkers["db"])
+	start := time.Now()
+	code, report := ready()
+	if code != http.StatusServiceUnavailable || report.Status != StatusDown {
+		t.Error("should not be ready", code, report)
+	}
+	if report.Checks["db"].Error != "connection refused" {
+		t.Error("unexpected error of db", report.Checks["db"])
+	}
+	if report.Checks["slow"].Status != StatusDown || time.Since(start) > 2*time.Second {
+		t.Error("slow check should time out", report.Checks["slow"], time.Since(start))
+	}
+
+	r.ShuttingDown()
+	if code, report := ready(); code != http.StatusServiceUnavailable || !report.ShuttingDown {
+		t.Error("should not be ready when shutting down", code, report)
+	}
+	w := httptest.NewRecorder()
+	r.LivenessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, LivenessPath, nil))
+	if w.Code != http.StatusOK {
+		t.Error("should be alive when shutting down", w.Code)
+	}
+}
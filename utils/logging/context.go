@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/xid"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -14,8 +15,14 @@ const (
 	CtxLoggerName = "ctx"
 	// TraceIDKeyName define the trace id keyname
 	TraceIDKeyName = "trace_id"
+	// SpanIDKeyName define the span id keyname, it's set if the context has a span of the trace
+	SpanIDKeyName = "span_id"
 	// TraceIDHeaderName ...
 	TraceIDHeaderName = "X-Trace-Id"
+	// UserIDKeyName define the user id keyname, it's the user of sentry events as well
+	UserIDKeyName = "user_id"
+	// APIKeyIDKeyName define the api key id keyname
+	APIKeyIDKeyName = "api_key_id"
 )
 
 func getLoggerFromCtx(c context.Context) *zap.Logger {
@@ -64,10 +71,24 @@ func CtxTraceID(c context.Context) string {
 	if traceIDItf != nil {
 		return traceIDItf.(string)
 	}
+	if sc := spanContext(c); sc.IsValid() {
+		return sc.TraceID().String()
+	}
 	// return default value
 	return xid.New().String()
 }
 
+// spanContext returns span context of opentelemetry span in context, of its request for *gin.Context
+func spanContext(c context.Context) trace.SpanContext {
+	if gc, ok := c.(*gin.Context); ok {
+		if gc.Request == nil {
+			return trace.SpanContext{}
+		}
+		c = gc.Request.Context()
+	}
+	return trace.SpanContextFromContext(c)
+}
+
 // NewCtxLogger return a context with logger and trace id and a logger with trace id
 func NewCtxLogger(c context.Context, logger *zap.Logger, traceID string) (context.Context, *zap.Logger) {
 	if c == nil {
@@ -77,6 +98,9 @@ func NewCtxLogger(c context.Context, logger *zap.Logger, traceID string) (contex
 		traceID = CtxTraceID(c)
 	}
 	ctxLogger := logger.With(zap.String(TraceIDKeyName, traceID))
+	if sc := spanContext(c); sc.IsValid() && sc.TraceID().String() == traceID {
+		ctxLogger = ctxLogger.With(zap.String(SpanIDKeyName, sc.SpanID().String()))
+	}
 	if gc, ok := c.(*gin.Context); ok {
 		gc.Set(CtxLoggerName, ctxLogger)
 		gc.Set(TraceIDKeyName, traceID)
@@ -86,3 +110,17 @@ func NewCtxLogger(c context.Context, logger *zap.Logger, traceID string) (contex
 	}
 	return c, ctxLogger
 }
+
+// AddCtxFields adds fields to ctxLogger of context, for *gin.Context the ctxLogger of its request is updated as well.
+// Other contexts should be replaced by the returned one.
+func AddCtxFields(c context.Context, fields ...zap.Field) context.Context {
+	ctxLogger := CtxLogger(c, fields...)
+	if gc, ok := c.(*gin.Context); ok {
+		gc.Set(CtxLoggerName, ctxLogger)
+		if gc.Request != nil {
+			gc.Request = gc.Request.WithContext(context.WithValue(gc.Request.Context(), CtxLoggerName, ctxLogger))
+		}
+		return gc
+	}
+	return context.WithValue(c, CtxLoggerName, ctxLogger)
+}
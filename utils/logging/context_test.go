@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestGinContext(t *testing.T) {
@@ -94,3 +95,22 @@ func TestCtxLoggerDefaultLoggerWithField(t *testing.T) {
 	}
 	ctxlogger.Info("this is a logger from default logger with field")
 }
+
+func TestAddCtxFields(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	NewCtxLogger(c, zap.New(core), "1234")
+
+	AddCtxFields(c, zap.Uint(UserIDKeyName, 7))
+	CtxLogger(c).Info("gin")
+	CtxLogger(c.Request.Context()).Info("request")
+	for _, entry := range logs.All() {
+		if entry.ContextMap()[UserIDKeyName] != uint64(7) {
+			t.Errorf("%s: user_id not added, %v", entry.Message, entry.ContextMap())
+		}
+	}
+	if logs.Len() != 2 {
+		t.Error("unexpected logs", logs.Len())
+	}
+}
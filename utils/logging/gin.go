@@ -68,6 +68,9 @@ type GinLoggerConfig struct {
 	EnableDetails bool
 	// slow request threshold, output Error log if request handle time exeeded this value
 	SlowThreshold time.Duration
+	// SlowThresholdFunc returns slow request threshold for each request, overrides SlowThreshold
+	// Optional. Use it to change threshold at runtime
+	SlowThresholdFunc func() time.Duration
 
 	skipPathMap map[string]struct{}
 	skipRegexps []*regexp.Regexp
@@ -159,6 +162,9 @@ func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
 			}
 		}
 		_, ctxLogger := NewCtxLogger(c, ginLogger, traceID)
+		// services get ctxLogger from context of request
+		reqCtx := context.WithValue(c.Request.Context(), TraceIDKeyName, traceID)
+		c.Request = c.Request.WithContext(context.WithValue(reqCtx, CtxLoggerName, ctxLogger))
 
 		start := time.Now()
 
@@ -186,7 +192,8 @@ func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
 			details.Timestamp = time.Now()
 			details.Latency = details.Timestamp.Sub(start).Seconds()
 
-			makeLog(ctxLogger, details, c, conf)
+			// fields may be added by handlers, e.g. user_id
+			makeLog(CtxLogger(c), details, c, conf)
 		}()
 
 		c.Next()
@@ -264,11 +271,17 @@ func makeLog(ctxLogger *zap.Logger, details GinLogDetails, c *gin.Context, conf
 		}
 	}
 	if !skipLog {
+		slowThreshold := conf.SlowThreshold
+		if conf.SlowThresholdFunc != nil {
+			if d := conf.SlowThresholdFunc(); d > 0 {
+				slowThreshold = d
+			}
+		}
 		// Warn log for slow request
-		if details.Latency > conf.SlowThreshold.Seconds() {
+		if details.Latency > slowThreshold.Seconds() {
 			logger.Warn(
 				conf.Formatter(details)+" hit slow request.",
-				zap.Float64("slow_threshold", conf.SlowThreshold.Seconds()),
+				zap.Float64("slow_threshold", slowThreshold.Seconds()),
 			)
 		} else {
 			log(conf.Formatter(details))
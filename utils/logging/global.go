@@ -97,3 +97,11 @@ func SentryCaptureException(err error) error {
 	sentry.CaptureException(err)
 	return nil
 }
+
+// Sync flushes buffered logs of global logger and pending sentry events
+func Sync() {
+	_ = zapLogger.Sync()
+	if SentryClient() != nil {
+		sentry.Flush(2 * time.Second)
+	}
+}
@@ -54,6 +54,8 @@ var (
 		"fatal":  zap.NewAtomicLevelAt(zap.FatalLevel),
 	}
 	rwMutex sync.RWMutex
+	// sentryMutex guards sentryClient
+	sentryMutex sync.RWMutex
 )
 
 // Options new logger options
@@ -111,8 +113,16 @@ func init() {
 	}
 }
 
-// InitLogger init logger
+// InitLogger init logger, errors are captured by the default sentry client
 func InitLogger(name string, logDir string, debug bool) (*zap.Logger, error) {
+	logger, err := initLogger(name, logDir, debug)
+	if err != nil {
+		return nil, err
+	}
+	return SentryAttach(logger, nil), nil
+}
+
+func initLogger(name string, logDir string, debug bool) (*zap.Logger, error) {
 	logPath := filepath.Join(logDir, "/access.log")
 	if debug {
 		outputPath := []string{"lumberjack:", "stderr"}
@@ -244,9 +254,18 @@ func SetLevel(lvl string) {
 
 // SentryClient get sentry client
 func SentryClient() *sentry.Client {
+	sentryMutex.RLock()
+	defer sentryMutex.RUnlock()
 	return sentryClient
 }
 
+// ReplaceSentryClient replace default sentry client, nil disables sentry of InitLogger loggers
+func ReplaceSentryClient(client *sentry.Client) {
+	sentryMutex.Lock()
+	defer sentryMutex.Unlock()
+	sentryClient = client
+}
+
 // ServerIP get server IP
 func ServerIP() string {
 	conn, err := net.Dial("udp", "8.8.8.8:80")
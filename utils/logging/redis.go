@@ -0,0 +1,104 @@
+// go-redis v8
+
+package logging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// RedisLoggerName is name of redis logger
+var RedisLoggerName = "redis"
+
+type redisStartKey struct{}
+
+// RedisLogger use zap to print internal logs of go-redis, set it by redis.SetLogger
+type RedisLogger struct{}
+
+// Printf implements go-redis internal.Logging interface method
+func (RedisLogger) Printf(ctx context.Context, format string, v ...interface{}) {
+	CtxLogger(ctx).Named(RedisLoggerName).Sugar().Warnf(format, v...)
+}
+
+// RedisHook logs failed and slow redis commands with ctx logger
+type RedisHook struct {
+	slowThreshold time.Duration
+}
+
+// NewRedisHook make RedisHook, commands slower than slowThreshold are logged as warning
+func NewRedisHook(slowThreshold time.Duration) *RedisHook {
+	return &RedisHook{slowThreshold: slowThreshold}
+}
+
+// BeforeProcess implements redis hook interface method
+func (h *RedisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+// AfterProcess implements redis hook interface method
+func (h *RedisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.log(ctx, cmd.Name(), redisCmdSummary(cmd), cmd.Err())
+	return nil
+}
+
+// BeforeProcessPipeline implements redis hook interface method
+func (h *RedisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+// AfterProcessPipeline implements redis hook interface method
+func (h *RedisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	names := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		names = append(names, cmd.Name())
+		if cmd.Err() != nil && cmd.Err() != redis.Nil && err == nil {
+			err = cmd.Err()
+		}
+	}
+	h.log(ctx, "pipeline", strings.Join(names, " "), err)
+	return nil
+}
+
+// redisCmdSummary returns name and first key of cmd, other arguments and the reply are omitted
+// as they may hold cached records and tokens
+func redisCmdSummary(cmd redis.Cmder) string {
+	args := cmd.Args()
+	key := 1
+	switch cmd.Name() {
+	case "eval", "evalsha":
+		// eval script numkeys key ...
+		key = 3
+		if len(args) < 3 || fmt.Sprint(args[2]) == "0" {
+			key = len(args)
+		}
+	}
+	if key >= len(args) {
+		return cmd.Name()
+	}
+	return fmt.Sprintf("%s %v", cmd.Name(), args[key])
+}
+
+func (h *RedisHook) log(ctx context.Context, name, cmd string, err error) {
+	start, _ := ctx.Value(redisStartKey{}).(time.Time)
+	latency := time.Since(start).Seconds()
+	switch {
+	case err != nil && err != redis.Nil:
+		CtxLogger(ctx).Named(RedisLoggerName).Error(
+			fmt.Sprintf("redis %s: %s", name, cmd),
+			zap.Float64("latency", latency),
+			zap.String("error", err.Error()),
+		)
+	case h.slowThreshold != 0 && latency > h.slowThreshold.Seconds():
+		CtxLogger(ctx).Named(RedisLoggerName).Warn(
+			fmt.Sprintf("redis %s: %s", name, cmd),
+			zap.Float64("latency", latency),
+			zap.Float64("threshold", h.slowThreshold.Seconds()),
+		)
+	}
+}
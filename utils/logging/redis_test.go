@@ -0,0 +1,27 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestRedisCmdSummary(t *testing.T) {
+	ctx := context.Background()
+	set := redis.NewStatusCmd(ctx, "set", "user:7", `{"email":"a@example.com"}`, "ex", 60)
+	set.SetVal("OK")
+	for _, tc := range []struct {
+		cmd  redis.Cmder
+		want string
+	}{
+		{set, "set user:7"},
+		{redis.NewStatusCmd(ctx, "ping"), "ping"},
+		{redis.NewCmd(ctx, "evalsha", "sha", 1, "ratelimit:api:user:7", 600), "evalsha ratelimit:api:user:7"},
+		{redis.NewCmd(ctx, "eval", "return 1", 0), "eval"},
+	} {
+		if got := redisCmdSummary(tc.cmd); got != tc.want {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+}
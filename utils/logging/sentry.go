@@ -3,9 +3,11 @@
 package logging
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/getsentry/sentry-go"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -50,9 +52,12 @@ type sentryCore struct {
 	fields map[string]interface{}
 }
 
-// GetSentryClient return sentry client
+// GetSentryClient return sentry client, the default sentry client is used if client of core is nil
 func (c *sentryCore) GetSentryClient() *sentry.Client {
-	return c.client
+	if c.client != nil {
+		return c.client
+	}
+	return SentryClient()
 }
 
 func (c *sentryCore) with(fs []zapcore.Field) *sentryCore {
@@ -78,6 +83,7 @@ func (c *sentryCore) with(fs []zapcore.Field) *sentryCore {
 		cfg:          c.cfg,
 		fields:       m,
 		LevelEnabler: c.LevelEnabler,
+		flushTimeout: c.flushTimeout,
 	}
 }
 
@@ -96,6 +102,10 @@ func (c *sentryCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore
 
 // Write zap core interface
 func (c *sentryCore) Write(ent zapcore.Entry, fs []zapcore.Field) error {
+	client := c.GetSentryClient()
+	if client == nil {
+		return nil
+	}
 	clone := c.with(fs)
 
 	event := sentry.NewEvent()
@@ -105,6 +115,20 @@ func (c *sentryCore) Write(ent zapcore.Entry, fs []zapcore.Field) error {
 	event.Platform = "go"
 	event.Extra = clone.fields
 	event.Tags = c.cfg.Tags
+	if userID, ok := clone.fields[UserIDKeyName]; ok {
+		event.User = sentry.User{ID: fmt.Sprint(userID)}
+	}
+
+	// link event to the trace of opentelemetry
+	if traceID, ok := clone.fields[TraceIDKeyName].(string); ok {
+		if _, err := trace.TraceIDFromHex(traceID); err == nil {
+			traceCtx := sentry.Context{"trace_id": traceID}
+			if spanID, ok := clone.fields[SpanIDKeyName].(string); ok {
+				traceCtx["span_id"] = spanID
+			}
+			event.Contexts["trace"] = traceCtx
+		}
+	}
 
 	if !c.cfg.DisableStacktrace {
 		trace := sentry.NewStacktrace()
@@ -121,22 +145,24 @@ func (c *sentryCore) Write(ent zapcore.Entry, fs []zapcore.Field) error {
 	if hub == nil {
 		hub = sentry.CurrentHub()
 	}
-	_ = c.client.CaptureEvent(event, nil, hub.Scope())
+	_ = client.CaptureEvent(event, nil, hub.Scope())
 
 	// We may be crashing the program, so should flush any buffered events.
 	if ent.Level > zapcore.ErrorLevel {
-		c.client.Flush(c.flushTimeout)
+		client.Flush(c.flushTimeout)
 	}
 	return nil
 }
 
 // Sync zap core interface
 func (c *sentryCore) Sync() error {
-	c.client.Flush(c.flushTimeout)
+	if client := c.GetSentryClient(); client != nil {
+		client.Flush(c.flushTimeout)
+	}
 	return nil
 }
 
-// NewSentryCore new a sentry core
+// NewSentryCore new a sentry core, the default sentry client is used if sentryClient is nil
 func NewSentryCore(cfg SentryCoreConfig, sentryClient *sentry.Client) zapcore.Core {
 
 	core := sentryCore{
@@ -173,6 +199,20 @@ func NewSentryClient(dsn string, debug bool) (*sentry.Client, error) {
 	return client, nil
 }
 
+// SetSentryDSN replace default sentry client with a new client of dsn, empty dsn disables it
+func SetSentryDSN(dsn string, debug bool) error {
+	if dsn == "" {
+		ReplaceSentryClient(nil)
+		return nil
+	}
+	client, err := NewSentryClient(dsn, debug)
+	if err != nil {
+		return err
+	}
+	ReplaceSentryClient(client)
+	return nil
+}
+
 // SentryAttach attach sentrycore
 func SentryAttach(l *zap.Logger, sentryClient *sentry.Client) *zap.Logger {
 	cfg := SentryCoreConfig{
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"encoding/json"
+
+	"github.com/dean2032/go-project-layout/utils/errors"
+)
+
+// MergePatch applies JSON merge patch (RFC 7386) to doc:
+// members of patch replace those of doc, null removes the member,
+// objects are merged recursively and any other value replaces doc as a whole
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var d, p interface{}
+	if len(doc) > 0 {
+		if err := json.Unmarshal(doc, &d); err != nil {
+			return nil, errors.CodeWrap(errors.InputError, err, "invalid json document")
+		}
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, errors.CodeWrap(errors.InputError, err, "invalid json merge patch")
+	}
+	data, err := json.Marshal(mergeValue(d, p))
+	return data, errors.WithStack(err)
+}
+
+func mergeValue(doc, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	d, ok := doc.(map[string]interface{})
+	if !ok {
+		d = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(d, k)
+			continue
+		}
+		d[k] = mergeValue(d[k], v)
+	}
+	return d
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// startTimeKey key of statement start time in gorm instance
+const startTimeKey = "metrics:start_time"
+
+// GormPlugin observes duration of gorm statements
+type GormPlugin struct {
+	metrics *Metrics
+}
+
+// GormPlugin returns gorm plugin recording gorm_query_duration_seconds
+func (m *Metrics) GormPlugin() *GormPlugin {
+	return &GormPlugin{metrics: m}
+}
+
+// Name ...
+func (p *GormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize registers callbacks around statements of every operation
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, h := range hooks {
+		if err := h.before("metrics:before_"+h.operation, before); err != nil {
+			return err
+		}
+		if err := h.after("metrics:after_"+h.operation, p.after(h.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func before(db *gorm.DB) {
+	db.InstanceSet(startTimeKey, time.Now())
+}
+
+func (p *GormPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(startTimeKey)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		p.metrics.queryDuration.WithLabelValues(operation, table).Observe(time.Since(v.(time.Time)).Seconds())
+	}
+}
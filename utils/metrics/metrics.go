@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dean2032/go-project-layout/utils/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/fx"
+)
+
+// Module provides metrics of the app
+var Module = fx.Provide(New)
+
+// Metrics prometheus collectors of http requests, errors and database queries
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	inFlight        prometheus.Gauge
+	errors          *prometheus.CounterVec
+	queryDuration   *prometheus.HistogramVec
+}
+
+// New creates metrics registered to a new registry with go runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Count of http requests by route template, method and status.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Latency of http requests by route template, method and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Count of http requests being served.",
+		}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "app_errors_total",
+			Help: "Count of error responses by code of errors.CodeError.",
+		}, []string{"code"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gorm_query_duration_seconds",
+			Help:    "Latency of gorm statements by operation and table.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"operation", "table"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.inFlight,
+		m.errors,
+		m.queryDuration,
+	)
+	return m
+}
+
+// Registry returns registry of metrics, to register collectors of other components
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler returns http handler serving metrics in prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RequestStarted increases in-flight requests, the returned func records the finished request
+func (m *Metrics) RequestStarted() func(route, method string, status int, seconds float64) {
+	m.inFlight.Inc()
+	return func(route, method string, status int, seconds float64) {
+		m.inFlight.Dec()
+		s := strconv.Itoa(status)
+		m.requests.WithLabelValues(route, method, s).Inc()
+		m.requestDuration.WithLabelValues(route, method, s).Observe(seconds)
+	}
+}
+
+// Error counts error response of code
+func (m *Metrics) Error(code int) {
+	m.errors.WithLabelValues(strconv.Itoa(code)).Inc()
+}
+
+// RegisterDB registers pool stats of primary and replicas as go_sql_* metrics labeled by db_name
+func (m *Metrics) RegisterDB(primary *sql.DB, replicas []*sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(primary, "primary"))
+	for i, replica := range replicas {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(replica, fmt.Sprintf("replica_%d", i)))
+	}
+}
+
+// RegisterCache registers hit and miss counters of cache loader of name
+func (m *Metrics) RegisterCache(name string, stats func() cache.Stats) {
+	labels := prometheus.Labels{"cache": name}
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "cache_hits_total",
+			Help:        "Count of cache hits.",
+			ConstLabels: labels,
+		}, func() float64 { return float64(stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "cache_misses_total",
+			Help:        "Count of cache misses.",
+			ConstLabels: labels,
+		}, func() float64 { return float64(stats().Misses) }),
+	)
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dean2032/go-project-layout/utils/cache"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	return string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	done := m.RequestStarted()
+	if out := scrape(t, m); !strings.Contains(out, "http_requests_in_flight 1") {
+		t.Error("request should be in flight")
+	}
+	done("/api/user/:id", "GET", 404, 0.01)
+	m.Error(4)
+	m.RegisterCache("user", func() cache.Stats { return cache.Stats{Hits: 3, Misses: 1} })
+
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:3306)/test",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(m.GormPlugin()); err != nil {
+		t.Fatal(err)
+	}
+	var rows []struct{ ID uint }
+	db.Table("users").Find(&rows)
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		"http_requests_in_flight 0",
+		`http_requests_total{method="GET",route="/api/user/:id",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/api/user/:id",status="404"} 1`,
+		`app_errors_total{code="4"} 1`,
+		`cache_hits_total{cache="user"} 3`,
+		`gorm_query_duration_seconds_count{operation="query",table="users"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(out, want) {
+			t.Error("missing", want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval interval to drop keys whose buckets are full
+const sweepInterval = time.Minute
+
+// MemoryLimiter limiter of a single instance
+type MemoryLimiter struct {
+	mu sync.Mutex
+	// tats theoretical arrival time of next request by key
+	tats      map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryLimiter creates limiter keeping state in memory
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		tats:      map[string]time.Time{},
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow ...
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+	tat, result := gcra(l.tats[key], now, limit)
+	l.tats[key] = tat
+	return result, nil
+}
+
+// sweep drops keys whose tat passed, they are the same as new keys
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	for key, tat := range l.tats {
+		if tat.Before(now) {
+			delete(l.tats, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit requests allowed per period, all of them may be sent in a burst
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// interval emission interval of requests, one request is allowed again after it
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// Result decision of a request
+type Result struct {
+	Allowed bool
+	// Remaining requests allowed at once
+	Remaining int
+	// RetryAfter wait time until next request is allowed, 0 if allowed
+	RetryAfter time.Duration
+	// Reset wait time until all requests of limit are allowed again
+	Reset time.Duration
+}
+
+// Limiter limits requests of keys by GCRA (generic cell rate algorithm), a token bucket
+// keeping theoretical arrival time of next request only
+type Limiter interface {
+	// Allow takes one request of key by limit
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// gcra takes a request at now of key with theoretical arrival time tat, returns tat after it
+func gcra(tat, now time.Time, limit Limit) (time.Time, Result) {
+	if tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(limit.interval())
+	allowAt := newTat.Add(-limit.Period)
+	if now.Before(allowAt) {
+		return tat, Result{RetryAfter: allowAt.Sub(now), Reset: tat.Sub(now)}
+	}
+	return newTat, allowed(newTat.Sub(now), limit)
+}
+
+// allowed result of allowed request with reset after it
+func allowed(reset time.Duration, limit Limit) Result {
+	return Result{
+		Allowed:   true,
+		Remaining: int((limit.Period - reset) / limit.interval()),
+		Reset:     reset,
+	}
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestLimiters(t *testing.T) {
+	start := time.Now().Truncate(time.Second)
+	memory := NewMemoryLimiter()
+	memoryNow := start
+	memory.now = func() time.Time { return memoryNow }
+
+	mr := miniredis.RunT(t)
+	mr.SetTime(start)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	limiters := map[string]struct {
+		limiter Limiter
+		advance func(d time.Duration)
+	}{
+		"memory": {memory, func(d time.Duration) { memoryNow = memoryNow.Add(d) }},
+		"redis": {NewRedisLimiter(client, "ratelimit:"), func(d time.Duration) {
+			start = start.Add(d)
+			mr.SetTime(start)
+		}},
+	}
+	ctx := context.Background()
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			for i := 2; i >= 0; i-- {
+				r, err := l.limiter.Allow(ctx, "k", limit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !r.Allowed || r.Remaining != i {
+					t.Errorf("request %d: got %+v, want allowed with %d remaining", 3-i, r, i)
+				}
+			}
+			r, _ := l.limiter.Allow(ctx, "k", limit)
+			if r.Allowed || r.RetryAfter != time.Second || r.Reset != 3*time.Second {
+				t.Errorf("burst exceeded: got %+v, want retry after 1s and reset in 3s", r)
+			}
+			if r, _ := l.limiter.Allow(ctx, "other", limit); !r.Allowed {
+				t.Error("keys should be limited separately")
+			}
+			l.advance(time.Second)
+			if r, _ := l.limiter.Allow(ctx, "k", limit); !r.Allowed || r.Remaining != 0 {
+				t.Errorf("one request should be allowed after interval, got %+v", r)
+			}
+			l.advance(3 * time.Second)
+			if r, _ := l.limiter.Allow(ctx, "k", limit); !r.Allowed || r.Remaining != 2 {
+				t.Errorf("bucket should be full after period, got %+v", r)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/go-redis/redis/v8"
+)
+
+// gcraScript gcra of ratelimit.go in microseconds by clock of redis, so that instances agree on time.
+// returns allowed, retry after and reset
+var gcraScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local tat = tonumber(redis.call("GET", KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+local new_tat = tat + interval
+local allow_at = new_tat - period
+if now < allow_at then
+	return {0, allow_at - now, tat - now}
+end
+redis.call("SET", KEYS[1], string.format("%.0f", new_tat), "PX", math.ceil((new_tat - now) / 1000))
+return {1, 0, new_tat - now}
+`)
+
+// RedisLimiter limiter shared by all instances of service
+type RedisLimiter struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisLimiter creates limiter storing keys with prefix in redis
+func NewRedisLimiter(client redis.UniversalClient, prefix string) *RedisLimiter {
+	return &RedisLimiter{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Allow ...
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	values, err := gcraScript.Run(ctx, l.client, []string{l.prefix + key},
+		limit.interval().Microseconds(), limit.Period.Microseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, errors.Wrap(err, "run rate limit script")
+	}
+	reset := time.Duration(values[2]) * time.Microsecond
+	if values[0] == 0 {
+		return Result{RetryAfter: time.Duration(values[1]) * time.Microsecond, Reset: reset}, nil
+	}
+	return allowed(reset, limit), nil
+}
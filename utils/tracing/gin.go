@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware starts server span of each request, it's the child of traceparent of request if any.
+// Without traceparent, the span adopts X-Trace-Id as its trace id if it's 32 hex digits.
+// traceparent of the span is sent in response headers, install it before logging.GinLogger
+// for trace ids of logs to match spans.
+func Middleware(tp trace.TracerProvider) gin.HandlerFunc {
+	tracer := tp.Tracer(TracerName)
+	return func(c *gin.Context) {
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			if traceID, err := trace.TraceIDFromHex(c.GetHeader(logging.TraceIDHeaderName)); err == nil {
+				ctx = context.WithValue(ctx, legacyTraceIDKey{}, traceID)
+			}
+		}
+		route := c.FullPath()
+		name := "HTTP " + c.Request.Method
+		if route != "" {
+			name = c.Request.Method + " " + route
+		}
+		ctx, span := tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest("", route, c.Request)...))
+		defer span.End()
+		propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(c.Writer.Header()))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(status)...)
+		span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(status, trace.SpanKindServer))
+		if len(c.Errors) > 0 {
+			span.SetAttributes(attribute.String("gin.errors", c.Errors.String()))
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/dean2032/go-project-layout/config"
+	"github.com/dean2032/go-project-layout/utils/errors"
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
+)
+
+// Module provides tracer provider of the app and http client of outbound calls
+var Module = fx.Provide(NewTracerProvider, NewHTTPClient)
+
+// TracerName instrumentation name of spans created by the app
+const TracerName = "github.com/dean2032/go-project-layout"
+
+// propagator W3C trace context and baggage
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// NewTracerProvider creates tracer provider exporting spans by config, it's set as the global provider.
+// Spans are created even if exporter is none, their trace ids are logged.
+func NewTracerProvider(lc fx.Lifecycle, cfg *config.Config) (*sdktrace.TracerProvider, error) {
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+		sdktrace.WithIDGenerator(idGenerator{}),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceNameKey.String(cfg.Tracing.ServiceName))),
+	}
+	exporter, err := newExporter(cfg.Tracing)
+	if err != nil {
+		return nil, err
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			logging.Info("flushing spans")
+			return errors.Wrap(tp.Shutdown(ctx), "shutdown tracer provider")
+		},
+	})
+	return tp, nil
+}
+
+func newExporter(cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case config.TracingExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		return exporter, errors.Wrap(err, "create stdout span exporter")
+	case config.TracingExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(context.Background(), opts...)
+		return exporter, errors.Wrap(err, "create otlp span exporter")
+	}
+	return nil, nil
+}
+
+// NewHTTPClient creates http client of outbound calls, a client span is created for each call
+// and trace context is injected to its headers
+func NewHTTPClient(tp *sdktrace.TracerProvider) *http.Client {
+	return &http.Client{
+		Transport: otelhttp.NewTransport(http.DefaultTransport,
+			otelhttp.WithTracerProvider(tp),
+			otelhttp.WithPropagators(propagator)),
+		Timeout: 30 * time.Second,
+	}
+}
+
+// legacyTraceIDKey context key of trace id given by X-Trace-Id, for root span of request
+type legacyTraceIDKey struct{}
+
+// idGenerator random ids, root span of request adopts trace id of X-Trace-Id if it's a W3C trace id
+type idGenerator struct{}
+
+// NewIDs sdktrace.IDGenerator interface
+func (g idGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	traceID, ok := ctx.Value(legacyTraceIDKey{}).(trace.TraceID)
+	if !ok {
+		_, _ = rand.Read(traceID[:])
+	}
+	return traceID, g.NewSpanID(ctx, traceID)
+}
+
+// NewSpanID sdktrace.IDGenerator interface
+func (g idGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	var spanID trace.SpanID
+	_, _ = rand.Read(spanID[:])
+	return spanID
+}
@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dean2032/go-project-layout/utils/logging"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder), sdktrace.WithIDGenerator(idGenerator{}))
+	app := gin.New()
+	app.Use(Middleware(tp), logging.GinLogger())
+	var loggedTraceID string
+	app.GET("/users/:id", func(c *gin.Context) {
+		loggedTraceID = logging.CtxTraceID(c)
+		if c.Param("id") == "0" {
+			c.Status(http.StatusInternalServerError)
+		}
+	})
+
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parent  = "00f067aa0ba902b7"
+	)
+	cases := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		// wantTraceID trace id of span, empty for a new one
+		wantTraceID string
+		// wantLogged trace id of logs, empty for the one of span
+		wantLogged string
+		wantParent string
+		wantStatus codes.Code
+	}{
+		{
+			name:        "traceparent",
+			path:        "/users/1",
+			headers:     map[string]string{"traceparent": "00-" + traceID + "-" + parent + "-01"},
+			wantTraceID: traceID,
+			wantParent:  parent,
+		},
+		{
+			name:        "w3c X-Trace-Id",
+			path:        "/users/1",
+			headers:     map[string]string{logging.TraceIDHeaderName: traceID},
+			wantTraceID: traceID,
+		},
+		{
+			name:       "legacy X-Trace-Id",
+			path:       "/users/1",
+			headers:    map[string]string{logging.TraceIDHeaderName: "legacy-id"},
+			wantLogged: "legacy-id",
+		},
+		{
+			name:       "server error",
+			path:       "/users/0",
+			wantStatus: codes.Error,
+		},
+	}
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+
+			spans := recorder.Ended()
+			if len(spans) != i+1 {
+				t.Fatalf("got %d spans, want %d", len(spans), i+1)
+			}
+			span := spans[i]
+			if span.Name() != "GET /users/:id" {
+				t.Error("unexpected span name", span.Name())
+			}
+			got := span.SpanContext().TraceID().String()
+			if tc.wantTraceID != "" && got != tc.wantTraceID {
+				t.Errorf("trace id of span: got %s, want %s", got, tc.wantTraceID)
+			}
+			if tc.wantParent != "" && span.Parent().SpanID().String() != tc.wantParent {
+				t.Errorf("parent of span: got %s, want %s", span.Parent().SpanID(), tc.wantParent)
+			}
+			if tc.wantParent == "" && span.Parent().IsValid() {
+				t.Error("span should be a root span")
+			}
+			wantLogged := tc.wantLogged
+			if wantLogged == "" {
+				wantLogged = got
+			}
+			if loggedTraceID != wantLogged {
+				t.Errorf("trace id of logs: got %s, want %s", loggedTraceID, wantLogged)
+			}
+			if h := w.Header().Get(logging.TraceIDHeaderName); h != wantLogged {
+				t.Errorf("%s of response: got %s, want %s", logging.TraceIDHeaderName, h, wantLogged)
+			}
+			wantParentHeader := "00-" + got + "-" + span.SpanContext().SpanID().String() + "-01"
+			if h := w.Header().Get("traceparent"); h != wantParentHeader {
+				t.Errorf("traceparent of response: got %s, want %s", h, wantParentHeader)
+			}
+			if span.Status().Code != tc.wantStatus {
+				t.Errorf("status of span: got %v, want %v", span.Status().Code, tc.wantStatus)
+			}
+		})
+	}
+}